@@ -7,10 +7,12 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/0xProject/rpc-gateway/internal/admin"
 	"github.com/0xProject/rpc-gateway/internal/metrics"
 	"github.com/0xProject/rpc-gateway/internal/rpcgateway"
+	"github.com/0xProject/rpc-gateway/internal/tracing"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
@@ -48,11 +50,16 @@ func main() {
 		logger.Fatal("failed to get config", zap.Error(err))
 	}
 
+	shutdownTracing, err := tracing.Setup(topCtx, config.Tracing)
+	if err != nil {
+		logger.Fatal("failed to set up tracing", zap.Error(err))
+	}
+
 	// start gateway
 	rpcGateway := rpcgateway.NewRPCGateway(*config)
 
-	// start healthz and metrics server
-	metricsServer := metrics.NewServer(config.Metrics)
+	// start healthz, readiness and metrics server
+	metricsServer := metrics.NewServer(config.Metrics, rpcGateway)
 	g.Go(func() error {
 		return metricsServer.Start()
 	})
@@ -62,28 +69,39 @@ func main() {
 		logger.Fatal("failed to get admin server config", zap.Error(err))
 	}
 	// start administration server
-	adminServer := admin.NewServer(*adminConfig, rpcGateway)
+	adminServer := admin.NewServer(*adminConfig, rpcGateway, zapConfig.Level)
 	g.Go(func() error {
 		return adminServer.Start()
 	})
 
+	// The health checks keep running while the requests in flight are
+	// drained, rpcGateway.Stop stops them.
 	g.Go(func() error {
-		return rpcGateway.Start(context.TODO())
+		return rpcGateway.Start(context.Background())
 	})
 
 	g.Go(func() error {
 		<-gCtx.Done()
-		err := metricsServer.Stop()
+		// Report unhealthy first so that load balancers stop sending traffic
+		// while the requests in flight are drained.
+		metricsServer.Drain()
+		err := rpcGateway.Stop(context.Background())
 		if err != nil {
-			logger.Error("error when stopping healthserver", zap.Error(err))
+			logger.Error("error when stopping rpc gateway", zap.Error(err))
 		}
 		err = adminServer.Stop()
 		if err != nil {
 			logger.Error("error when stopping admin Server", zap.Error(err))
 		}
-		err = rpcGateway.Stop(context.TODO())
+		err = metricsServer.Stop()
 		if err != nil {
-			logger.Error("error when stopping rpc gateway", zap.Error(err))
+			logger.Error("error when stopping healthserver", zap.Error(err))
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = shutdownTracing(ctx)
+		if err != nil {
+			logger.Error("error when flushing traces", zap.Error(err))
 		}
 		return nil
 	})
@@ -1,6 +1,7 @@
 package admin
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -18,9 +19,15 @@ import (
 
 type TargetManager interface {
     GetBlockNumberByName(name string) uint64
+    GetCapabilitiesByName(name string) map[string]bool
     GetTargetConfigs() []proxy.TargetConfig
     GetTargetConfigByName(name string) *proxy.TargetConfig
     UpdateTargetStatus(targetconfig *proxy.TargetConfig, isDisabled bool)
+    GetStickySessions() []proxy.StickySession
+    AddDebugRule(target, method string, duration time.Duration) proxy.DebugRule
+    GetDebugRules() []proxy.DebugRule
+    ClearDebugRules()
+    GetSLOs() []proxy.TargetSLO
 }
 
 type Server struct {
@@ -29,14 +36,18 @@ type Server struct {
 
 func (s *Server) Start() error {
 	zap.L().Info("Administration server starting", zap.String("listenAddr", s.server.Addr))
-	return s.server.ListenAndServe()
+	if err := s.server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
 }
 
 func (s *Server) Stop() error {
 	return s.server.Close()
 }
 
-func NewServer(config AdminServerConfig, targetManager TargetManager) *Server {
+func NewServer(config AdminServerConfig, targetManager TargetManager, logLevel zap.AtomicLevel) *Server {
 	r := mux.NewRouter()
 
 	r.Use(
@@ -52,6 +63,12 @@ func NewServer(config AdminServerConfig, targetManager TargetManager) *Server {
 
 	adminRouter.HandleFunc("/targets/{name}", UpdateTargetHandler(targetManager)).Methods("POST")
 	adminRouter.HandleFunc("/targets", GetTargetsHandler(targetManager)).Methods("GET")
+	adminRouter.HandleFunc("/sessions", GetSessionsHandler(targetManager)).Methods("GET")
+	adminRouter.HandleFunc("/slo", GetSLOsHandler(targetManager)).Methods("GET")
+	adminRouter.Handle("/log/level", logLevel).Methods("GET", "PUT")
+	adminRouter.HandleFunc("/log/debug", GetDebugRulesHandler(targetManager)).Methods("GET")
+	adminRouter.HandleFunc("/log/debug", AddDebugRuleHandler(targetManager)).Methods("POST")
+	adminRouter.HandleFunc("/log/debug", ClearDebugRulesHandler(targetManager)).Methods("DELETE")
 
     r.PathPrefix("/").Handler(DefaultHandler{})
 
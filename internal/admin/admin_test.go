@@ -8,8 +8,11 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/0xProject/rpc-gateway/internal/proxy"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func createConfig() AdminServerConfig {
@@ -26,12 +29,20 @@ const (
 
 type MockTargetManager struct{
     targetConfigs []proxy.TargetConfig
+    debugRules    []proxy.DebugRule
 }
 
 func (m *MockTargetManager) GetBlockNumberByName(name string) uint64 {
 	return 100500
 }
 
+func (m *MockTargetManager) GetCapabilitiesByName(name string) map[string]bool {
+	if name == "Server1" {
+		return map[string]bool{"trace_block": false}
+	}
+	return nil
+}
+
 func (m *MockTargetManager) GetTargetConfigs() []proxy.TargetConfig {
     return m.targetConfigs
 }
@@ -54,9 +65,35 @@ func (m *MockTargetManager) UpdateTargetStatus(targetconfig *proxy.TargetConfig,
     }
 }
 
+func (m *MockTargetManager) GetStickySessions() []proxy.StickySession {
+	return []proxy.StickySession{
+		{Client: "10.0.0.1", Target: "Server1", LastSeen: time.Unix(1708608427, 0).UTC()},
+	}
+}
+
+func (m *MockTargetManager) AddDebugRule(target, method string, duration time.Duration) proxy.DebugRule {
+	rule := proxy.DebugRule{Target: target, Method: method, Until: time.Unix(1708608427, 0).Add(duration).UTC()}
+	m.debugRules = append(m.debugRules, rule)
+	return rule
+}
+
+func (m *MockTargetManager) GetDebugRules() []proxy.DebugRule {
+	return m.debugRules
+}
+
+func (m *MockTargetManager) ClearDebugRules() {
+	m.debugRules = nil
+}
+
+func (m *MockTargetManager) GetSLOs() []proxy.TargetSLO {
+	return []proxy.TargetSLO{
+		{Target: "Server1", Window: "5m0s", Requests: 10, Checks: 5, P50Ms: 12.5, P95Ms: 40, P99Ms: 80, ErrorRate: 0.1, Availability: 1},
+	}
+}
+
 func TestGeneratePayload(t *testing.T) {
     mockTargetManager := &MockTargetManager{}
-    server := NewServer(createConfig(), mockTargetManager)
+    server := NewServer(createConfig(), mockTargetManager, zap.NewAtomicLevel())
 
     requestBody := []byte(`{"address":"0x6Dcbf665293BDDe2237c1A6Af41fd70E969883F0"}`)
 
@@ -109,7 +146,7 @@ func TestListTargets(t *testing.T) {
 			{Name: "Server2", IsDisabled: false},
 		},
 	}
-	server := NewServer(createConfig(), targetManager)
+	server := NewServer(createConfig(), targetManager, zap.NewAtomicLevel())
 
     req, err := http.NewRequest("GET", "/admin/targets", nil)
     if err != nil {
@@ -125,7 +162,7 @@ func TestListTargets(t *testing.T) {
 	if status := rr.Code; status != http.StatusOK {
 		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
 	}
-	expectedResponseBody := `[{"name":"Server1","disabled":true,"blockNumber":100500},{"name":"Server2","disabled":false,"blockNumber":100500}]`
+	expectedResponseBody := `[{"name":"Server1","disabled":true,"blockNumber":100500,"capabilities":{"trace_block":false}},{"name":"Server2","disabled":false,"blockNumber":100500}]`
     if strings.TrimRight(rr.Body.String(), " \n\t") != expectedResponseBody {
         t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expectedResponseBody)
     }
@@ -138,7 +175,7 @@ func TestUpdateTargetStatus(t *testing.T) {
 			{Name: "Server2", IsDisabled: false},
 		},
 	}
-	server := NewServer(createConfig(), targetManager)
+	server := NewServer(createConfig(), targetManager, zap.NewAtomicLevel())
 
     requestBody := []byte(`{"disabled":true}`)
     req, err := http.NewRequest("POST", "/admin/targets/Server2", bytes.NewBuffer(requestBody))
@@ -170,7 +207,7 @@ func TestUpdateTargetStatusWhenNotInAdminList(t *testing.T) {
 	}
 	config := createConfig()
 	config.Admins = []string{""}
-	server := NewServer(config, targetManager)
+	server := NewServer(config, targetManager, zap.NewAtomicLevel())
 
     requestBody := []byte(`{"disabled":true}`)
     req, err := http.NewRequest("POST", "/admin/targets/Server2", bytes.NewBuffer(requestBody))
@@ -192,3 +229,121 @@ func TestUpdateTargetStatusWhenNotInAdminList(t *testing.T) {
         t.Errorf("handler has changed target's status: got %v want %v", targetConfig.IsDisabled, false)
     }
 }
+
+func TestListSessions(t *testing.T) {
+	server := NewServer(createConfig(), &MockTargetManager{}, zap.NewAtomicLevel())
+
+	req, err := http.NewRequest("GET", "/admin/sessions", nil)
+	if err != nil {
+		t.Fatalf("error creating request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+validAuthToken)
+
+	// execute request
+	rr := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(rr, req)
+
+	// assert
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	expectedResponseBody := `[{"client":"10.0.0.1","target":"Server1","lastSeen":"2024-02-22T13:27:07Z"}]`
+	if strings.TrimRight(rr.Body.String(), " \n\t") != expectedResponseBody {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expectedResponseBody)
+	}
+}
+
+func TestListSLOs(t *testing.T) {
+	server := NewServer(createConfig(), &MockTargetManager{}, zap.NewAtomicLevel())
+
+	req, err := http.NewRequest("GET", "/admin/slo", nil)
+	if err != nil {
+		t.Fatalf("error creating request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+validAuthToken)
+
+	// execute request
+	rr := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(rr, req)
+
+	// assert
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	expectedResponseBody := `[{"target":"Server1","window":"5m0s","requests":10,"checks":5,"p50Ms":12.5,"p95Ms":40,"p99Ms":80,"errorRate":0.1,"availability":1}]`
+	if strings.TrimRight(rr.Body.String(), " \n\t") != expectedResponseBody {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expectedResponseBody)
+	}
+}
+
+func TestChangeLogLevel(t *testing.T) {
+	logLevel := zap.NewAtomicLevelAt(zapcore.WarnLevel)
+	server := NewServer(createConfig(), &MockTargetManager{}, logLevel)
+
+	req, err := http.NewRequest("PUT", "/admin/log/level", bytes.NewBufferString(`{"level":"debug"}`))
+	if err != nil {
+		t.Fatalf("error creating request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+validAuthToken)
+
+	// execute request
+	rr := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(rr, req)
+
+	// assert
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	if logLevel.Level() != zapcore.DebugLevel {
+		t.Errorf("handler hasn't changed the log level: got %v want %v", logLevel.Level(), zapcore.DebugLevel)
+	}
+}
+
+func TestAddDebugRule(t *testing.T) {
+	targetManager := &MockTargetManager{
+		targetConfigs: []proxy.TargetConfig{
+			{Name: "Server1"},
+		},
+	}
+	server := NewServer(createConfig(), targetManager, zap.NewAtomicLevel())
+
+	serve := func(method string, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, "/admin/log/debug", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("error creating request: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+validAuthToken)
+
+		rr := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if status := serve("POST", `{"target":"Server3"}`).Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+	if status := serve("POST", `{"target":"Server1","duration":"often"}`).Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+
+	rr := serve("POST", `{"target":"Server1","method":"eth_call","duration":"10m"}`)
+	if status := rr.Code; status != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+	}
+	expectedResponseBody := `{"target":"Server1","method":"eth_call","until":"2024-02-22T13:37:07Z"}`
+	if strings.TrimRight(rr.Body.String(), " \n\t") != expectedResponseBody {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expectedResponseBody)
+	}
+
+	rr = serve("GET", "")
+	if strings.TrimRight(rr.Body.String(), " \n\t") != "["+expectedResponseBody+"]" {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), "["+expectedResponseBody+"]")
+	}
+
+	if status := serve("DELETE", "").Code; status != http.StatusNoContent {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNoContent)
+	}
+	if len(targetManager.debugRules) != 0 {
+		t.Errorf("handler hasn't cleared the debug rules: got %v", targetManager.debugRules)
+	}
+}
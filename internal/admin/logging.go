@@ -0,0 +1,56 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+func GetDebugRulesHandler(targetManager TargetManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(targetManager.GetDebugRules())
+	}
+}
+
+func AddDebugRuleHandler(targetManager TargetManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestBody struct {
+			Target   string `json:"target"`
+			Method   string `json:"method"`
+			Duration string `json:"duration"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, "Failed to decode JSON body", http.StatusBadRequest)
+			return
+		}
+
+		var duration time.Duration
+		if requestBody.Duration != "" {
+			var err error
+			if duration, err = time.ParseDuration(requestBody.Duration); err != nil {
+				http.Error(w, "Field 'duration' is not a duration", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if requestBody.Target != "" && targetManager.GetTargetConfigByName(requestBody.Target) == nil {
+			http.Error(w, "Target not found", http.StatusNotFound)
+			return
+		}
+
+		rule := targetManager.AddDebugRule(requestBody.Target, requestBody.Method, duration)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(rule)
+	}
+}
+
+func ClearDebugRulesHandler(targetManager TargetManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		targetManager.ClearDebugRules()
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,13 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func GetSessionsHandler(targetManager TargetManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(targetManager.GetStickySessions())
+	}
+}
@@ -10,6 +10,7 @@ type TargetInfo struct {
     Name        string `json:"name"`
     Disabled    bool   `json:"disabled"`
     BlockNumber uint64 `json:"blockNumber"`
+    Capabilities map[string]bool `json:"capabilities,omitempty"`
 }
 
 func GetTargetsHandler(targetManager TargetManager) http.HandlerFunc {
@@ -22,6 +23,7 @@ func GetTargetsHandler(targetManager TargetManager) http.HandlerFunc {
 				Name:     target.Name,
 				Disabled: target.IsDisabled,
 				BlockNumber: targetManager.GetBlockNumberByName(target.Name),
+				Capabilities: targetManager.GetCapabilitiesByName(target.Name),
 			}
 
 			targetInfos = append(targetInfos, targetInfo)
@@ -2,4 +2,18 @@ package metrics
 
 type Config struct {
 	Port uint `yaml:"port"`
+	// Prefix of every metric name, defaults to rpc_gateway.
+	Namespace string `yaml:"namespace"`
+	// Constant labels added to every metric when set.
+	Chain    string `yaml:"chain"`
+	Instance string `yaml:"instance"`
+	// Keep emitting the metrics under their names from before the
+	// namespace was configurable, during a migration. Defaults to true
+	// until the old names are removed.
+	LegacyNames *bool `yaml:"legacyNames"`
+}
+
+// legacyNames reports whether the metrics are emitted under their old names too.
+func (c Config) legacyNames() bool {
+	return c.LegacyNames == nil || *c.LegacyNames
 }
@@ -1,8 +1,10 @@
 package metrics
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -11,26 +13,37 @@ import (
 
 type Server struct {
 	server *http.Server
+	// set once the gateway is shutting down, /healthz and /readyz then
+	// report it unhealthy so that load balancers stop sending traffic.
+	draining  atomic.Bool
+	readiness Readiness
 }
 
 func (s *Server) Start() error {
 	zap.L().Info("metrics server starting", zap.String("listenAddr", s.server.Addr))
-	return s.server.ListenAndServe()
+	if err := s.server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Drain makes /healthz and /readyz report the gateway unhealthy, the
+// metrics are still served until Stop.
+func (s *Server) Drain() {
+	s.draining.Store(true)
 }
 
 func (s *Server) Stop() error {
 	return s.server.Close()
 }
 
-func NewServer(config Config) *Server {
+// NewServer serves the metrics and the health endpoints: /livez, /readyz
+// reflecting readiness, always ready when nil, and /healthz.
+func NewServer(config Config, readiness Readiness) *Server {
 	mux := http.NewServeMux()
-
-	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, "{\"healthy\":true}")
-	})
-	mux.Handle("/metrics", promhttp.Handler())
-
-	return &Server{
+	s := &Server{
+		readiness: readiness,
 		server: &http.Server{
 			Handler:           mux,
 			Addr:              fmt.Sprintf(":%d", config.Port),
@@ -39,4 +52,18 @@ func NewServer(config Config) *Server {
 			ReadHeaderTimeout: 5 * time.Second,
 		},
 	}
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if s.draining.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "{\"healthy\":false}")
+			return
+		}
+		fmt.Fprintf(w, "{\"healthy\":true}")
+	})
+	mux.HandleFunc("/livez", s.livez)
+	mux.HandleFunc("/readyz", s.readyz)
+	mux.Handle("/metrics", promhttp.Handler())
+
+	return s
 }
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeReadiness struct {
+	report ReadinessReport
+}
+
+func (f *fakeReadiness) Readiness() ReadinessReport {
+	return f.report
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	readiness := &fakeReadiness{report: ReadinessReport{Reason: "0 ready targets, 1 required", MinReadyTargets: 1}}
+	server := NewServer(Config{}, readiness)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		server.server.Handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, get("/livez").Code)
+	assert.Equal(t, http.StatusOK, get("/healthz").Code)
+
+	rr := get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	var report ReadinessReport
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, "0 ready targets, 1 required", report.Reason)
+
+	readiness.report = ReadinessReport{Ready: true, Started: true, ReadyTargets: 1, MinReadyTargets: 1}
+	assert.Equal(t, http.StatusOK, get("/readyz").Code)
+
+	// Once draining, only the liveness endpoint reports the gateway up.
+	server.Drain()
+	assert.Equal(t, http.StatusOK, get("/livez").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, get("/healthz").Code)
+	rr = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"reason":"draining"`)
+}
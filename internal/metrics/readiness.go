@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Readiness reports whether the gateway can serve traffic. It's implemented
+// by the gateway, which knows about the targets.
+type Readiness interface {
+	Readiness() ReadinessReport
+}
+
+// ReadinessReport is the body of /readyz.
+type ReadinessReport struct {
+	Ready bool `json:"ready"`
+	// Why the gateway is not ready.
+	Reason string `json:"reason,omitempty"`
+	// Whether every target completed its first health check round.
+	Started         bool              `json:"started"`
+	ReadyTargets    int               `json:"readyTargets"`
+	MinReadyTargets int               `json:"minReadyTargets"`
+	Targets         []TargetReadiness `json:"targets"`
+}
+
+// TargetReadiness is the state of a target as seen by the readiness check.
+type TargetReadiness struct {
+	Name     string `json:"name"`
+	Ready    bool   `json:"ready"`
+	Checked  bool   `json:"checked"`
+	Healthy  bool   `json:"healthy"`
+	Tainted  bool   `json:"tainted,omitempty"`
+	Diverged bool   `json:"diverged,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+	// Block number of the target and how far it is behind the head of the
+	// targets of its chain.
+	BlockNumber uint64 `json:"blockNumber"`
+	Lag         uint64 `json:"lag"`
+}
+
+func (s *Server) livez(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"alive":true}`)) // nolint:errcheck
+}
+
+func (s *Server) readyz(w http.ResponseWriter, _ *http.Request) {
+	report := ReadinessReport{Ready: true, Started: true}
+	if s.readiness != nil {
+		report = s.readiness.Readiness()
+	}
+	if s.draining.Load() {
+		report.Ready = false
+		report.Reason = "draining"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report) // nolint:errcheck
+}
@@ -0,0 +1,216 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const defaultNamespace = "rpc_gateway"
+
+// Desc describes a metric. Legacy is the full name the metric had before
+// the namespace was configurable, emitted along with LegacyLabels in
+// compatibility mode. The labels are passed in the same order to both.
+type Desc struct {
+	Name         string
+	Help         string
+	Labels       []string
+	Legacy       string
+	LegacyLabels []string
+	// Histograms only.
+	Buckets []float64
+	// Summaries only.
+	Objectives map[float64]float64
+	MaxAge     time.Duration
+}
+
+// Registry creates the gateway metrics with a common namespace and constant
+// labels.
+type Registry struct {
+	config     Config
+	registerer prometheus.Registerer
+}
+
+func NewRegistry(config Config, registerer prometheus.Registerer) *Registry {
+	if config.Namespace == "" {
+		config.Namespace = defaultNamespace
+	}
+
+	return &Registry{
+		config:     config,
+		registerer: registerer,
+	}
+}
+
+func (r *Registry) constLabels() prometheus.Labels {
+	labels := prometheus.Labels{}
+	if r.config.Chain != "" {
+		labels["chain"] = r.config.Chain
+	}
+	if r.config.Instance != "" {
+		labels["instance"] = r.config.Instance
+	}
+
+	return labels
+}
+
+// legacy reports whether desc is emitted under its old name too.
+func (r *Registry) legacy(desc Desc) bool {
+	return r.config.legacyNames() && desc.Legacy != ""
+}
+
+// register registers collector, or returns the one registered before with
+// the same description.
+func register[T prometheus.Collector](r *Registry, collector T) T {
+	err := r.registerer.Register(collector)
+	if err == nil {
+		return collector
+	}
+
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		if existing, ok := registered.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+
+	panic(err)
+}
+
+type CounterVec struct {
+	vecs []*prometheus.CounterVec
+}
+
+func (r *Registry) NewCounterVec(desc Desc) *CounterVec {
+	counter := &CounterVec{}
+	counter.vecs = append(counter.vecs, register(r, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   r.config.Namespace,
+		Name:        desc.Name,
+		Help:        desc.Help,
+		ConstLabels: r.constLabels(),
+	}, desc.Labels)))
+
+	if r.legacy(desc) {
+		counter.vecs = append(counter.vecs, register(r, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: desc.Legacy,
+			Help: desc.Help,
+		}, desc.LegacyLabels)))
+	}
+
+	return counter
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	for _, vec := range c.vecs {
+		vec.WithLabelValues(labelValues...).Add(value)
+	}
+}
+
+type GaugeVec struct {
+	vecs []*prometheus.GaugeVec
+}
+
+func (r *Registry) NewGaugeVec(desc Desc) *GaugeVec {
+	gauge := &GaugeVec{}
+	gauge.vecs = append(gauge.vecs, register(r, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   r.config.Namespace,
+		Name:        desc.Name,
+		Help:        desc.Help,
+		ConstLabels: r.constLabels(),
+	}, desc.Labels)))
+
+	if r.legacy(desc) {
+		gauge.vecs = append(gauge.vecs, register(r, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: desc.Legacy,
+			Help: desc.Help,
+		}, desc.LegacyLabels)))
+	}
+
+	return gauge
+}
+
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	for _, vec := range g.vecs {
+		vec.WithLabelValues(labelValues...).Set(value)
+	}
+}
+
+type HistogramVec struct {
+	vecs []*prometheus.HistogramVec
+}
+
+func (r *Registry) NewHistogramVec(desc Desc) *HistogramVec {
+	histogram := &HistogramVec{}
+	histogram.vecs = append(histogram.vecs, register(r, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   r.config.Namespace,
+		Name:        desc.Name,
+		Help:        desc.Help,
+		ConstLabels: r.constLabels(),
+		Buckets:     desc.Buckets,
+	}, desc.Labels)))
+
+	if r.legacy(desc) {
+		histogram.vecs = append(histogram.vecs, register(r, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    desc.Legacy,
+			Help:    desc.Help,
+			Buckets: desc.Buckets,
+		}, desc.LegacyLabels)))
+	}
+
+	return histogram
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	for _, vec := range h.vecs {
+		vec.WithLabelValues(labelValues...).Observe(value)
+	}
+}
+
+// ObserveLegacy observes value with legacyLabelValues on the legacy metric,
+// whose labels held something else than the current ones.
+func (h *HistogramVec) ObserveLegacy(value float64, labelValues, legacyLabelValues []string) {
+	for i, vec := range h.vecs {
+		if i > 0 {
+			labelValues = legacyLabelValues
+		}
+		vec.WithLabelValues(labelValues...).Observe(value)
+	}
+}
+
+type SummaryVec struct {
+	vecs []*prometheus.SummaryVec
+}
+
+func (r *Registry) NewSummaryVec(desc Desc) *SummaryVec {
+	summary := &SummaryVec{}
+	summary.vecs = append(summary.vecs, register(r, prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Namespace:   r.config.Namespace,
+		Name:        desc.Name,
+		Help:        desc.Help,
+		ConstLabels: r.constLabels(),
+		Objectives:  desc.Objectives,
+		MaxAge:      desc.MaxAge,
+	}, desc.Labels)))
+
+	if r.legacy(desc) {
+		summary.vecs = append(summary.vecs, register(r, prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Name:       desc.Legacy,
+			Help:       desc.Help,
+			Objectives: desc.Objectives,
+			MaxAge:     desc.MaxAge,
+		}, desc.LegacyLabels)))
+	}
+
+	return summary
+}
+
+func (s *SummaryVec) Observe(value float64, labelValues ...string) {
+	for _, vec := range s.vecs {
+		vec.WithLabelValues(labelValues...).Observe(value)
+	}
+}
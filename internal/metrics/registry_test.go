@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	registry := NewRegistry(Config{Chain: "ethereum"}, registerer)
+
+	desc := Desc{
+		Name:         "target_block_number",
+		Help:         "Block number of a given target",
+		Labels:       []string{"target"},
+		Legacy:       "zeroex_rpc_gateway_provider_block_number",
+		LegacyLabels: []string{"provider"},
+	}
+	registry.NewGaugeVec(desc).Set(42, "Infura")
+	// Registering again returns the same metric.
+	registry.NewGaugeVec(desc).Set(43, "Alchemy")
+
+	expected := `
+# HELP rpc_gateway_target_block_number Block number of a given target
+# TYPE rpc_gateway_target_block_number gauge
+rpc_gateway_target_block_number{chain="ethereum",target="Alchemy"} 43
+rpc_gateway_target_block_number{chain="ethereum",target="Infura"} 42
+# HELP zeroex_rpc_gateway_provider_block_number Block number of a given target
+# TYPE zeroex_rpc_gateway_provider_block_number gauge
+zeroex_rpc_gateway_provider_block_number{provider="Alchemy"} 43
+zeroex_rpc_gateway_provider_block_number{provider="Infura"} 42
+`
+	assert.Nil(t, testutil.GatherAndCompare(registerer, strings.NewReader(expected)))
+}
+
+func TestRegistryWithoutLegacyNames(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+	legacyNames := false
+	registry := NewRegistry(Config{Namespace: "gateway", LegacyNames: &legacyNames}, registerer)
+
+	registry.NewCounterVec(Desc{
+		Name:   "firewall_blocked_total",
+		Help:   "Total number of calls rejected by the method firewall",
+		Labels: []string{"jsonrpc_method"},
+		Legacy: "allbridge_rpc_gateway_firewall_blocked_total",
+	}).Inc("debug_setHead")
+
+	expected := `
+# HELP gateway_firewall_blocked_total Total number of calls rejected by the method firewall
+# TYPE gateway_firewall_blocked_total counter
+gateway_firewall_blocked_total{jsonrpc_method="debug_setHead"} 1
+`
+	assert.Nil(t, testutil.GatherAndCompare(registerer, strings.NewReader(expected)))
+}
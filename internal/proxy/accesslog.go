@@ -0,0 +1,175 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const defaultAccessLogBodySize = 1024
+
+type accessLogger struct {
+	config AccessLogConfig
+	logger *zap.Logger
+}
+
+func newAccessLogger(config AccessLogConfig) *accessLogger {
+	if !config.Enabled {
+		return nil
+	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = defaultAccessLogBodySize
+	}
+
+	var writer zapcore.WriteSyncer = os.Stdout
+	if config.File != "" {
+		writer = zapcore.AddSync(&lumberjack.Logger{
+			Filename:   config.File,
+			MaxSize:    config.MaxSizeMB,
+			MaxBackups: config.MaxBackups,
+			MaxAge:     config.MaxAgeDays,
+			Compress:   config.Compress,
+		})
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoderConfig.LevelKey = zapcore.OmitKey
+	encoderConfig.CallerKey = zapcore.OmitKey
+
+	return &accessLogger{
+		config: config,
+		logger: zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), writer, zap.InfoLevel)),
+	}
+}
+
+// bodySize is how much of the response body is kept for the log, one byte
+// more than logged to tell it was truncated.
+func (l *accessLogger) bodySize() int {
+	if !l.config.Bodies {
+		return 0
+	}
+
+	return l.config.MaxBodySize + 1
+}
+
+// sampled reports whether the request is logged. Failed requests always are.
+func (l *accessLogger) sampled(history *RequestHistory, status int) bool {
+	if status >= http.StatusBadRequest || len(history.Failed()) > 0 {
+		return true
+	}
+	if l.config.SampleRate <= 0 || l.config.SampleRate >= 1 {
+		return true
+	}
+
+	return rand.Float64() < l.config.SampleRate // nolint:gosec
+}
+
+func (l *accessLogger) log(r *http.Request, history *RequestHistory, w *responseRecorder) {
+	if !l.sampled(history, w.status) {
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("requestId", history.ID),
+		zap.String("client", r.RemoteAddr),
+		zap.String("userAgent", r.UserAgent()),
+		zap.String("path", r.URL.Path),
+		zap.Int("status", w.status),
+		zap.Float64("durationMs", float64(time.Since(history.Start).Microseconds())/1000),
+		zap.Any("attempts", history.Attempts),
+	}
+	if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+		fields = append(fields, zap.String("forwardedFor", forwardedFor))
+	}
+	if attempt := history.last(); attempt != nil {
+		fields = append(fields, zap.String("target", attempt.Target))
+	}
+
+	body := GetRequestBodyFromContext(r)
+	if body != nil {
+		methods := make([]string, 0, len(body.Requests))
+		for _, request := range body.Requests {
+			methods = append(methods, request.Method)
+		}
+		fields = append(fields, zap.Strings("methods", methods))
+	}
+
+	if l.config.Bodies {
+		if body != nil {
+			fields = append(fields, zap.String("requestBody", truncate(body.Raw, l.config.MaxBodySize)))
+		}
+		fields = append(fields, zap.String("responseBody", truncate(w.body.Bytes(), l.config.MaxBodySize)))
+	}
+
+	l.logger.Info("access", fields...)
+}
+
+func truncate(data []byte, size int) string {
+	if len(data) <= size {
+		return string(data)
+	}
+
+	return string(data[:size]) + "..."
+}
+
+// responseRecorder keeps the status and the beginning of the body written to
+// the client.
+type responseRecorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	bodySize int
+}
+
+func newResponseRecorder(w http.ResponseWriter, bodySize int) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, bodySize: bodySize}
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if remaining := w.bodySize - w.body.Len(); remaining > 0 {
+		w.body.Write(data[:min(remaining, len(data))])
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets websocket upgrades through.
+func (w *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	w.status = http.StatusSwitchingProtocols
+
+	return hijacker.Hijack()
+}
+
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
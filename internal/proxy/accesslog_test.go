@@ -0,0 +1,111 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLog(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	// Whichever target is hit first fails.
+	var calls atomic.Int32
+	fakeRPCServer := func() *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x0000000000000000000000000000000000000000000000000000000000000001"}`))
+		}))
+	}
+	fakeRPC1Server := fakeRPCServer()
+	defer fakeRPC1Server.Close()
+	fakeRPC2Server := fakeRPCServer()
+	defer fakeRPC2Server.Close()
+
+	file := filepath.Join(t.TempDir(), "access.log")
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Proxy.AccessLog = AccessLogConfig{
+		Enabled:     true,
+		File:        file,
+		SampleRate:  0.000001,
+		Bodies:      true,
+		MaxBodySize: 32,
+	}
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "Server1",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPC1Server.URL,
+				},
+			},
+		},
+		{
+			Name: "Server2",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPC2Server.URL,
+				},
+			},
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	// The rerouted request is logged despite the sampling, the second one
+	// is sampled out.
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"eth_getStorageAt","params":[]}`))
+		assert.Nil(t, err)
+		req.Header.Set("X-Request-Id", "abc")
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	data, err := os.ReadFile(file)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 1)
+
+	var entry struct {
+		RequestID    string    `json:"requestId"`
+		Methods      []string  `json:"methods"`
+		Status       int       `json:"status"`
+		Target       string    `json:"target"`
+		Attempts     []Attempt `json:"attempts"`
+		RequestBody  string    `json:"requestBody"`
+		ResponseBody string    `json:"responseBody"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &entry))
+
+	assert.Equal(t, "abc", entry.RequestID)
+	assert.Equal(t, []string{"eth_getStorageAt"}, entry.Methods)
+	assert.Equal(t, http.StatusOK, entry.Status)
+	assert.Len(t, entry.Attempts, 2)
+	assert.Equal(t, http.StatusTooManyRequests, entry.Attempts[0].Status)
+	assert.Equal(t, "rate limited", entry.Attempts[0].Error)
+	assert.Equal(t, http.StatusOK, entry.Attempts[1].Status)
+	assert.Empty(t, entry.Attempts[1].Error)
+	assert.Equal(t, entry.Attempts[1].Target, entry.Target)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"method"...`, entry.RequestBody)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result"...`, entry.ResponseBody)
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"net/http"
+)
+
+// Full nodes keep the state of the last 128 blocks.
+const defaultArchiveDepth = 128
+
+// archiveFilter routes the calls for historical state, made against a block
+// too far behind the cluster head for a full node to serve, to archive
+// targets.
+func (h *Proxy) archiveFilter(r *http.Request) TargetFilter {
+	body := GetRequestBodyFromContext(r)
+	if body == nil || !h.needsArchive(body.Requests, h.clusterHead(h.targetGroups(r)[0])) {
+		return nil
+	}
+
+	return func(_ int, healthchecker Healthchecker) bool {
+		return healthchecker.IsArchive()
+	}
+}
+
+func (h *Proxy) needsArchive(requests []RPCRequest, clusterHead func() uint64) bool {
+	depth := h.config.Proxy.Archive.Depth
+	if depth == 0 {
+		depth = defaultArchiveDepth
+	}
+
+	var head uint64
+	for _, request := range requests {
+		block, ok := request.BlockReference()
+		if !ok {
+			continue
+		}
+		if block.Tag == "earliest" {
+			return true
+		}
+		if !block.HasNumber() {
+			continue
+		}
+
+		if head == 0 {
+			if head = clusterHead(); head == 0 {
+				return false
+			}
+		}
+		if block.Number+depth < head {
+			return true
+		}
+	}
+
+	return false
+}
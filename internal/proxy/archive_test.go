@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveProbe(t *testing.T) {
+	fakeRPCServer := func(message string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var request RPCRequest
+			json.NewDecoder(r.Body).Decode(&request)
+			if message == "" {
+				w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(request.ID) + `,"result":"0x0"}`))
+				return
+			}
+			w.Write(NewRPCError(request.ID, rpcErrorServer, message))
+		}))
+	}
+	archiveServer := fakeRPCServer("")
+	defer archiveServer.Close()
+	fullServer := fakeRPCServer("missing trie node 0x1 (path ) <nil>")
+	defer fullServer.Close()
+	rateLimitedServer := fakeRPCServer("daily request count exceeded, request rate limited")
+	defer rateLimitedServer.Close()
+
+	probe := func(url string) *RPCHealthchecker {
+		healthchecker, err := NewHealthchecker(RPCHealthcheckerConfig{
+			URL:     url,
+			Timeout: time.Second,
+		})
+		assert.Nil(t, err)
+		healthchecker.(*RPCHealthchecker).checkAndSetArchive()
+
+		return healthchecker.(*RPCHealthchecker)
+	}
+
+	assert.True(t, probe(archiveServer.URL).IsArchive())
+	assert.False(t, probe(fullServer.URL).IsArchive())
+	assert.True(t, probe(fullServer.URL).archiveProbed)
+
+	// A node that cannot be reached is probed again.
+	unreachable := probe("http://127.0.0.1:1")
+	assert.False(t, unreachable.IsArchive())
+	assert.False(t, unreachable.archiveProbed)
+
+	// So is a node answering with an error that does not tell the state is
+	// missing, e.g. a rate limit.
+	rateLimited := probe(rateLimitedServer.URL)
+	assert.False(t, rateLimited.IsArchive())
+	assert.False(t, rateLimited.archiveProbed)
+}
+
+func TestArchiveRouting(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	var received []string
+	fakeRPCServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = append(received, name)
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+		}))
+	}
+	fakeRPC1Server := fakeRPCServer("Full")
+	defer fakeRPC1Server.Close()
+	fakeRPC2Server := fakeRPCServer("Archive")
+	defer fakeRPC2Server.Close()
+
+	archive := true
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "Full",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPC1Server.URL,
+				},
+			},
+		},
+		{
+			Name: "Archive",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPC2Server.URL,
+				},
+			},
+			Archive: &archive,
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	markHealthy(healthcheckManager)
+	healthcheckManager.healthcheckers[0].(*RPCHealthchecker).blockNumber = 1000
+	healthcheckManager.healthcheckers[1].(*RPCHealthchecker).blockNumber = 1000
+
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	serve := func(body string) {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(body))
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	// Recent state is served by any target.
+	for i := 0; i < 16; i++ {
+		serve(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000000000","0x3e0"]}`)
+	}
+	assert.Contains(t, received, "Full")
+	assert.Contains(t, received, "Archive")
+
+	// Historical state only by the archive one.
+	received = nil
+	for i := 0; i < 8; i++ {
+		serve(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000000000","0x10"]}`)
+		serve(`{"jsonrpc":"2.0","id":1,"method":"eth_call","params":[{},"earliest"]}`)
+	}
+	assert.NotContains(t, received, "Full")
+}
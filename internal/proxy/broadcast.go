@@ -0,0 +1,264 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go.uber.org/zap"
+)
+
+const (
+	methodSendRawTransaction = "eth_sendRawTransaction"
+
+	// Broadcasts outlive the client request, so they get their own timeout.
+	broadcastTimeout = 30 * time.Second
+)
+
+type broadcastOutcome int
+
+const (
+	broadcastFailed broadcastOutcome = iota
+	broadcastAccepted
+	// The target already has the transaction, e.g. it got it from a peer.
+	broadcastKnown
+)
+
+type broadcastResult struct {
+	target  *HTTPTarget
+	body    []byte
+	outcome broadcastOutcome
+	reason  string
+}
+
+// isAlreadyKnownError matches the errors returned by nodes that already have
+// the transaction in their pool.
+func isAlreadyKnownError(message string) bool {
+	message = strings.ToLower(message)
+	for _, known := range []string{
+		"already known",
+		"known transaction",
+		"already imported",
+		"already exists",
+		"transaction already in",
+	} {
+		if strings.Contains(message, known) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *Proxy) isBroadcast(request *RPCRequest) bool {
+	return h.config.Proxy.Broadcast.Enabled && request.Method == methodSendRawTransaction
+}
+
+// broadcastTargets returns the targets of the first group request can be
+// routed to that has any, filtered like a single call.
+func (h *Proxy) broadcastTargets(r *http.Request) []*HTTPTarget {
+	subset := h.config.Proxy.Broadcast.Targets
+
+	for _, group := range h.targetGroups(r) {
+		var targets []*HTTPTarget
+		for _, target := range h.routableTargets(r, group) {
+			if len(subset) == 0 || slices.Contains(subset, target.Config.Name) {
+				targets = append(targets, target)
+			}
+		}
+
+		if len(targets) > 0 {
+			return targets
+		}
+	}
+
+	return nil
+}
+
+// serveBroadcast sends a raw transaction to all the broadcast targets in
+// parallel and responds with the first transaction hash returned, or the
+// computed one as soon as a target already knows the transaction. The
+// remaining targets are awaited in the background and the outcome is logged.
+func (h *Proxy) serveBroadcast(w http.ResponseWriter, r *http.Request, request *RPCRequest) {
+	targets := h.broadcastTargets(r)
+	if len(targets) == 0 {
+		http.Error(w, "Service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	body := GetRequestBodyFromContext(r).Raw
+	txHash := transactionHash(request)
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), broadcastTimeout)
+	results := make(chan broadcastResult, len(targets))
+	for _, target := range targets {
+		go func(target *HTTPTarget) {
+			results <- h.sendTransaction(ctx, target, body, txHash)
+		}(target)
+	}
+
+	collected := make([]broadcastResult, 0, len(targets))
+	var accepted, known *broadcastResult
+	for len(collected) < len(targets) && accepted == nil && known == nil {
+		result := <-results
+		collected = append(collected, result)
+		switch result.outcome {
+		case broadcastAccepted:
+			accepted = &result
+		case broadcastKnown:
+			known = &result
+		}
+	}
+
+	switch {
+	case accepted != nil:
+		w.Header().Set(providerHeader, accepted.target.Config.Name)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(accepted.body) // nolint:errcheck
+		h.observeResponseTime(r, accepted.target.Config.Name, time.Since(start))
+
+	case known != nil:
+		response, err := NewRPCResult(request.ID, txHash)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			break
+		}
+		w.Header().Set(providerHeader, known.target.Config.Name)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(response) // nolint:errcheck
+		h.observeResponseTime(r, known.target.Config.Name, time.Since(start))
+
+	default:
+		// None of the targets took the transaction, respond with the error
+		// of the first target that returned a JSON-RPC response. The body of
+		// a failed call may be anything, e.g. the text of a 500.
+		index := slices.IndexFunc(collected, func(result broadcastResult) bool {
+			_, err := ParseRPCResponse(result.body)
+			return err == nil
+		})
+		if index < 0 {
+			http.Error(w, "Service not available", http.StatusServiceUnavailable)
+			h.observeResponseTime(r, "broadcast", time.Since(start))
+			break
+		}
+		w.Header().Set(providerHeader, collected[index].target.Config.Name)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(collected[index].body) // nolint:errcheck
+		h.observeResponseTime(r, collected[index].target.Config.Name, time.Since(start))
+	}
+
+	go func() {
+		defer cancel()
+		for len(collected) < len(targets) {
+			collected = append(collected, <-results)
+		}
+		logBroadcast(txHash, collected)
+	}()
+}
+
+// sendTransaction submits the transaction to a single target and classifies
+// the response.
+func (h *Proxy) sendTransaction(ctx context.Context, target *HTTPTarget, body []byte, txHash string) broadcastResult {
+	result := broadcastResult{target: target, outcome: broadcastFailed}
+
+	data, err := target.Call(ctx, body)
+	result.body = data
+	if err != nil {
+		result.reason = err.Error()
+		return result
+	}
+
+	if message, ok := matchException(string(data), h.exceptionsOf(target.Config)); ok {
+		result.reason = message
+		return result
+	}
+
+	response, err := ParseRPCResponse(data)
+	if err != nil {
+		result.reason = err.Error()
+		return result
+	}
+
+	switch {
+	case response.Error == nil:
+		result.outcome = broadcastAccepted
+	case isAlreadyKnownError(response.Error.Message):
+		result.outcome = broadcastKnown
+	case strings.Contains(strings.ToLower(response.Error.Message), "nonce too low") &&
+		h.hasTransaction(ctx, target, txHash):
+		// The nonce is used by this very transaction, it's already mined.
+		result.outcome = broadcastKnown
+	default:
+		result.reason = response.Error.Message
+	}
+
+	return result
+}
+
+// hasTransaction checks whether the target knows a transaction by its hash.
+func (h *Proxy) hasTransaction(ctx context.Context, target *HTTPTarget, txHash string) bool {
+	if txHash == "" {
+		return false
+	}
+
+	params, _ := json.Marshal([]string{txHash})
+	body, err := json.Marshal(RPCRequest{JSONRPC: "2.0", ID: json.RawMessage("1"), Method: "eth_getTransactionByHash", Params: params})
+	if err != nil {
+		return false
+	}
+
+	data, err := target.Call(ctx, body)
+	if err != nil {
+		return false
+	}
+
+	response, err := ParseRPCResponse(data)
+	if err != nil {
+		return false
+	}
+
+	return response.Error == nil && !response.IsEmptyResult()
+}
+
+// transactionHash computes the hash of the raw transaction, the value a
+// successful eth_sendRawTransaction returns.
+func transactionHash(request *RPCRequest) string {
+	param, ok := request.Param(0)
+	if !ok {
+		return ""
+	}
+
+	var rawTx string
+	if err := json.Unmarshal(param, &rawTx); err != nil {
+		return ""
+	}
+
+	data, err := hexutil.Decode(rawTx)
+	if err != nil {
+		return ""
+	}
+
+	return crypto.Keccak256Hash(data).Hex()
+}
+
+func logBroadcast(txHash string, results []broadcastResult) {
+	var accepted, rejected []string
+	for _, result := range results {
+		if result.outcome == broadcastFailed {
+			rejected = append(rejected, result.target.Config.Name+": "+result.reason)
+			continue
+		}
+		accepted = append(accepted, result.target.Config.Name)
+	}
+
+	zap.L().Info("transaction broadcast",
+		zap.String("txHash", txHash),
+		zap.Strings("acceptedBy", accepted),
+		zap.Strings("rejectedBy", rejected))
+}
@@ -0,0 +1,205 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func createBroadcastProxy(t *testing.T, responses map[string]string, received *atomic.Int32) *Proxy {
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Proxy.Broadcast = BroadcastConfig{Enabled: true}
+	for _, name := range []string{"Server1", "Server2", "Server3"} {
+		response := responses[name]
+		fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received.Add(1)
+			if response == "" {
+				http.Error(w, "Bad Request", http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(response))
+		}))
+		t.Cleanup(fakeRPCServer.Close)
+
+		rpcGatewayConfig.Targets = append(rpcGatewayConfig.Targets, TargetConfig{
+			Name: name,
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPCServer.URL,
+				},
+			},
+		})
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	markHealthy(healthcheckManager)
+
+	return NewProxy(rpcGatewayConfig, healthcheckManager)
+}
+
+func serveRawTransaction(t *testing.T, httpFailoverProxy *Proxy) *httptest.ResponseRecorder {
+	requestBody := bytes.NewBufferString(`{"jsonrpc":"2.0","id":7,"method":"eth_sendRawTransaction","params":["0x01"]}`)
+	req, err := http.NewRequest("POST", "/", requestBody)
+	assert.Nil(t, err)
+
+	rr := httptest.NewRecorder()
+	httpFailoverProxy.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestBroadcastRawTransaction(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	txHash := crypto.Keccak256Hash([]byte{0x01}).Hex()
+	received := &atomic.Int32{}
+	httpFailoverProxy := createBroadcastProxy(t, map[string]string{
+		"Server1": `{"jsonrpc":"2.0","id":7,"result":"` + txHash + `"}`,
+		"Server2": `{"jsonrpc":"2.0","id":7,"error":{"code":-32000,"message":"insufficient funds for gas * price + value"}}`,
+	}, received)
+
+	rr := serveRawTransaction(t, httpFailoverProxy)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "Server1", rr.Header().Get("X-Rpc-Provider"))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":7,"result":"`+txHash+`"}`, rr.Body.String())
+
+	// Every target gets the transaction, including the ones that respond
+	// after the client got its answer.
+	assert.Eventually(t, func() bool {
+		return received.Load() == 3
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestBroadcastRawTransactionAlreadyKnown(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	txHash := crypto.Keccak256Hash([]byte{0x01}).Hex()
+	received := &atomic.Int32{}
+	httpFailoverProxy := createBroadcastProxy(t, map[string]string{
+		"Server1": `{"jsonrpc":"2.0","id":7,"error":{"code":-32000,"message":"insufficient funds for gas * price + value"}}`,
+		"Server2": `{"jsonrpc":"2.0","id":7,"error":{"code":-32000,"message":"already known"}}`,
+	}, received)
+
+	rr := serveRawTransaction(t, httpFailoverProxy)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "Server2", rr.Header().Get("X-Rpc-Provider"))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":7,"result":"`+txHash+`"}`, rr.Body.String())
+	assert.Eventually(t, func() bool {
+		return received.Load() == 3
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestBroadcastRawTransactionKnownDoesNotWait(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	release := make(chan struct{})
+	knownServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":7,"error":{"code":-32000,"message":"already known"}}`))
+	}))
+	defer knownServer.Close()
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slowServer.Close()
+	defer close(release)
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Proxy.Broadcast = BroadcastConfig{Enabled: true}
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{Name: "Known", Connection: TargetConfigConnection{HTTP: TargetConnectionHTTP{URL: knownServer.URL}}},
+		{Name: "Slow", Connection: TargetConfigConnection{HTTP: TargetConnectionHTTP{URL: slowServer.URL}}},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	markHealthy(healthcheckManager)
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	// The client gets the hash as soon as a target knows the transaction,
+	// the slow target is awaited in the background.
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- serveRawTransaction(t, httpFailoverProxy)
+	}()
+	select {
+	case rr := <-done:
+		txHash := crypto.Keccak256Hash([]byte{0x01}).Hex()
+		assert.Equal(t, "Known", rr.Header().Get("X-Rpc-Provider"))
+		assert.Equal(t, `{"jsonrpc":"2.0","id":7,"result":"`+txHash+`"}`, rr.Body.String())
+	case <-time.After(time.Second):
+		t.Fatal("the broadcast waited for the slow target")
+	}
+}
+
+func TestBroadcastTargetsFiltered(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	txHash := crypto.Keccak256Hash([]byte{0x01}).Hex()
+	var received [3]atomic.Int32
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Proxy.Broadcast = BroadcastConfig{Enabled: true}
+	for i, name := range []string{"Denied", "Unknown1", "Unknown2"} {
+		counter := &received[i]
+		fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			counter.Add(1)
+			w.Write([]byte(`{"jsonrpc":"2.0","id":7,"result":"` + txHash + `"}`))
+		}))
+		t.Cleanup(fakeRPCServer.Close)
+
+		target := TargetConfig{Name: name}
+		target.Connection.HTTP.URL = fakeRPCServer.URL
+		rpcGatewayConfig.Targets = append(rpcGatewayConfig.Targets, target)
+	}
+	rpcGatewayConfig.Targets[0].Firewall = FirewallConfig{Deny: []string{"eth_send*"}}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	denied := healthcheckManager.healthcheckers[0].(*RPCHealthchecker)
+	denied.checked = true
+	denied.isHealthy = true
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	// The firewall of the healthy target denies the method, the targets not
+	// checked yet are the last resort like for a single call.
+	rr := serveRawTransaction(t, httpFailoverProxy)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Eventually(t, func() bool {
+		return received[1].Load() == 1 && received[2].Load() == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(0), received[0].Load())
+}
+
+func TestBroadcastRawTransactionRejected(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	// The targets failing with a 500 are not answered with, their body is
+	// not a JSON-RPC response.
+	rejection := `{"jsonrpc":"2.0","id":7,"error":{"code":-32000,"message":"insufficient funds for gas * price + value"}}`
+	httpFailoverProxy := createBroadcastProxy(t, map[string]string{"Server2": rejection}, &atomic.Int32{})
+
+	rr := serveRawTransaction(t, httpFailoverProxy)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "Server2", rr.Header().Get("X-Rpc-Provider"))
+	assert.Equal(t, rejection, rr.Body.String())
+
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+	httpFailoverProxy = createBroadcastProxy(t, map[string]string{}, &atomic.Int32{})
+
+	rr = serveRawTransaction(t, httpFailoverProxy)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.uber.org/zap"
+)
+
+const defaultCapabilitiesInterval = 10 * time.Minute
+
+// checkAndSetCapabilities calls every probed method without parameters. A
+// node supporting the method answers with a result or an invalid params
+// error, other nodes with a method not found error.
+func (h *RPCHealthchecker) checkAndSetCapabilities() {
+	if len(h.config.Capabilities.Methods) == 0 {
+		return
+	}
+
+	interval := h.config.Capabilities.Interval
+	if interval == 0 {
+		interval = defaultCapabilitiesInterval
+	}
+
+	h.mu.Lock()
+	if time.Since(h.lastCapabilityProbes) < interval {
+		h.mu.Unlock()
+		return
+	}
+	h.lastCapabilityProbes = time.Now()
+	h.mu.Unlock()
+
+	for _, method := range h.config.Capabilities.Methods {
+		supported, ok := h.probeMethod(method)
+		if !ok {
+			continue
+		}
+
+		h.mu.Lock()
+		if h.capabilities == nil {
+			h.capabilities = map[string]bool{}
+		}
+		h.capabilities[method] = supported
+		h.mu.Unlock()
+	}
+}
+
+// probeMethod reports whether the node supports the method. The second
+// return value is false when the probe was inconclusive.
+func (h *RPCHealthchecker) probeMethod(method string) (bool, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	var result json.RawMessage
+	err := h.client.CallContext(ctx, &result, method)
+	if err == nil {
+		return true, true
+	}
+
+	var rpcErr rpc.Error
+	var httpErr rpc.HTTPError
+	switch {
+	case errors.As(err, &rpcErr):
+		return rpcErr.ErrorCode() != rpcErrorMethodNotFound && !isUnsupportedMessage(rpcErr.Error()), true
+	case errors.As(err, &httpErr):
+		// Some providers answer unsupported methods with a 4xx status.
+		if isUnsupportedMessage(string(httpErr.Body)) {
+			return false, true
+		}
+	}
+
+	zap.L().Warn("failed probing method", zap.Error(err), zap.String("method", method), zap.String("rpcProvider", h.config.Name))
+
+	return false, false
+}
+
+// isUnsupportedMessage matches the error messages providers use for the
+// methods they do not serve, most without the -32601 code.
+func isUnsupportedMessage(message string) bool {
+	message = strings.ToLower(message)
+	for _, match := range []string{
+		"method not found",
+		"does not exist",
+		"not available",
+		"not supported",
+		"unsupported method",
+		"not whitelisted",
+		"method not allowed",
+	} {
+		if strings.Contains(message, match) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Capabilities returns the probed methods and whether the node supports
+// them.
+func (h *RPCHealthchecker) Capabilities() map[string]bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return maps.Clone(h.capabilities)
+}
+
+// SupportsMethod reports whether the node is not known to lack the method.
+// A method that was not probed is judged by the probed methods of its
+// namespace, e.g. `trace_filter` by `trace_block`.
+func (h *RPCHealthchecker) SupportsMethod(method string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if supported, ok := h.capabilities[method]; ok {
+		return supported
+	}
+
+	namespace, _, found := strings.Cut(method, "_")
+	if !found {
+		return true
+	}
+
+	probed := false
+	for probe, supported := range h.capabilities {
+		if !strings.HasPrefix(probe, namespace+"_") {
+			continue
+		}
+		if supported {
+			return true
+		}
+		probed = true
+	}
+
+	return !probed
+}
+
+// capabilityFilter skips the targets known not to support one of the
+// requested methods.
+func capabilityFilter(r *http.Request) TargetFilter {
+	body := GetRequestBodyFromContext(r)
+	if body == nil || len(body.Requests) == 0 {
+		return nil
+	}
+
+	return func(_ int, healthchecker Healthchecker) bool {
+		for _, request := range body.Requests {
+			if !healthchecker.SupportsMethod(request.Method) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
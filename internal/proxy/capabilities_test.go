@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilityProbes(t *testing.T) {
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request RPCRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		switch request.Method {
+		case "trace_block":
+			w.Write(NewRPCError(request.ID, rpcErrorMethodNotFound, "the method trace_block does not exist/is not available"))
+		case "debug_traceBlockByNumber":
+			w.Write(NewRPCError(request.ID, -32602, "missing value for required argument 0"))
+		case "txpool_status":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":"txpool_status is not whitelisted"}`))
+		}
+	}))
+	defer fakeRPCServer.Close()
+
+	healthchecker, err := NewHealthchecker(RPCHealthcheckerConfig{
+		URL:     fakeRPCServer.URL,
+		Timeout: time.Second,
+		Capabilities: CapabilitiesConfig{
+			Methods: []string{"trace_block", "debug_traceBlockByNumber", "txpool_status"},
+		},
+	})
+	assert.Nil(t, err)
+	healthchecker.(*RPCHealthchecker).checkAndSetCapabilities()
+
+	assert.Equal(t, map[string]bool{
+		"trace_block":              false,
+		"debug_traceBlockByNumber": true,
+		"txpool_status":            false,
+	}, healthchecker.Capabilities())
+
+	assert.False(t, healthchecker.SupportsMethod("trace_block"))
+	// Judged by the namespace.
+	assert.False(t, healthchecker.SupportsMethod("trace_filter"))
+	assert.True(t, healthchecker.SupportsMethod("debug_traceTransaction"))
+	// Not probed at all.
+	assert.True(t, healthchecker.SupportsMethod("eth_getBlockReceipts"))
+}
+
+func TestCapabilityRouting(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	var received []string
+	fakeRPCServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = append(received, name)
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":[]}`))
+		}))
+	}
+	fakeRPC1Server := fakeRPCServer("Server1")
+	defer fakeRPC1Server.Close()
+	fakeRPC2Server := fakeRPCServer("Server2")
+	defer fakeRPC2Server.Close()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "Server1",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPC1Server.URL,
+				},
+			},
+		},
+		{
+			Name: "Server2",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPC2Server.URL,
+				},
+			},
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	healthcheckManager.healthcheckers[0].(*RPCHealthchecker).capabilities = map[string]bool{"trace_block": false}
+	healthcheckManager.healthcheckers[1].(*RPCHealthchecker).capabilities = map[string]bool{"trace_block": true}
+
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	for i := 0; i < 8; i++ {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"trace_filter","params":[{}]}`))
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+	assert.NotContains(t, received, "Server1")
+}
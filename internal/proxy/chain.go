@@ -0,0 +1,147 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+const (
+	ChainEVM    = "evm"
+	ChainSolana = "solana"
+	ChainTron   = "tron"
+	ChainSui    = "sui"
+)
+
+// chainFamily holds what differs between the JSON-RPC dialects of the chains
+// served by the gateway.
+type chainFamily interface {
+	// HeadHeight fetches the latest block number of the node, the slot on
+	// Solana, the checkpoint on Sui.
+	HeadHeight(ctx context.Context, h *RPCHealthchecker) (uint64, error)
+	// LivenessChecks are run on every health check, their failures count
+	// towards the failure threshold.
+	LivenessChecks(h *RPCHealthchecker) []probeCheck
+	// Exceptions are the errors of the nodes retried on a different target,
+	// on top of the exceptions of the config.
+	Exceptions() []Exception
+	// WebsocketOnNextPort reports whether the nodes serve websockets on the
+	// port next to the HTTP one, which the gateway then does too.
+	WebsocketOnNextPort() bool
+}
+
+// archiveProber is implemented by the families whose nodes may not serve
+// historical state.
+type archiveProber interface {
+	ProbeArchive(ctx context.Context, h *RPCHealthchecker) (bool, error)
+}
+
+func chainFamilyByName(name string) (chainFamily, error) {
+	switch name {
+	case "", ChainEVM:
+		return evmFamily{}, nil
+	case ChainSolana:
+		return solanaFamily{}, nil
+	case ChainTron:
+		return tronFamily{}, nil
+	case ChainSui:
+		return suiFamily{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown chain %q", name)
+}
+
+// ChainConfig selects the chain family of the targets: the one of their
+// group in Groups, Default otherwise. Families are named "evm" (the
+// default), "solana", "tron" and "sui".
+type ChainConfig struct {
+	Default string
+	Groups  map[string]string
+}
+
+// For returns the chain family name of target.
+func (c ChainConfig) For(target TargetConfig) string {
+	if chain, ok := c.Groups[target.Group]; ok && chain != "" {
+		return chain
+	}
+	if c.Default == "" {
+		return ChainEVM
+	}
+
+	return c.Default
+}
+
+// Validate reports the first unknown chain family.
+func (c ChainConfig) Validate() error {
+	if _, err := chainFamilyByName(c.Default); err != nil {
+		return err
+	}
+
+	groups := make([]string, 0, len(c.Groups))
+	for group := range c.Groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		if _, err := chainFamilyByName(c.Groups[group]); err != nil {
+			return fmt.Errorf("group %q: %w", group, err)
+		}
+	}
+
+	return nil
+}
+
+// checkHeadLag compares the last head of the node with the highest head of
+// the healthy nodes.
+func (h *RPCHealthchecker) checkHeadLag(maxLag uint64) error {
+	if h.config.ClusterHead == nil {
+		return nil
+	}
+
+	height := h.BlockNumber()
+	head := h.config.ClusterHead()
+	if height == 0 || head == 0 {
+		return nil
+	}
+	if head > height+maxLag {
+		return fmt.Errorf("head %d is %d behind the cluster head", height, head-height)
+	}
+
+	return nil
+}
+
+// observeHead records the head fetched by a call started at start.
+func (h *RPCHealthchecker) observeHead(method string, start time.Time, height uint64) {
+	if h.metricResponseTime != nil {
+		h.metricResponseTime.Observe(time.Since(start).Seconds(), h.config.Name, method)
+	}
+	if h.metricRPCProviderBlockNumber != nil {
+		h.metricRPCProviderBlockNumber.Set(float64(height), h.config.Name)
+	}
+}
+
+// clusterHead returns the cluster head of the chain of the targets of group,
+// the heights of the other chains are not compared.
+func (h *Proxy) clusterHead(group string) func() uint64 {
+	chain := h.config.Chain.For(TargetConfig{Group: group})
+
+	return func() uint64 { return h.healthcheckManager.highestBlockNumberOf(chain) }
+}
+
+// exceptionsOf returns the exceptions of the config and the errors known to
+// the chain family of target, retried on a different target alike.
+func (h *Proxy) exceptionsOf(target TargetConfig) []Exception {
+	return append(slices.Clip(h.config.Exceptions), h.config.Chain.chainFamilyOf(target).Exceptions()...)
+}
+
+// chainFamilyOf returns the family of target, EVM for an unknown one.
+func (c ChainConfig) chainFamilyOf(target TargetConfig) chainFamily {
+	family, err := chainFamilyByName(c.For(target))
+	if err != nil {
+		return evmFamily{}
+	}
+
+	return family
+}
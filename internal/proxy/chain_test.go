@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainConfig(t *testing.T) {
+	config := ChainConfig{
+		Default: ChainEVM,
+		Groups:  map[string]string{"sol": ChainSolana},
+	}
+	assert.Nil(t, config.Validate())
+	assert.Equal(t, ChainEVM, config.For(TargetConfig{Name: "Geth"}))
+	assert.Equal(t, ChainSolana, config.For(TargetConfig{Name: "Agave", Group: "sol"}))
+	assert.IsType(t, solanaFamily{}, config.chainFamilyOf(TargetConfig{Group: "sol"}))
+	assert.IsType(t, evmFamily{}, ChainConfig{}.chainFamilyOf(TargetConfig{}))
+	assert.IsType(t, tronFamily{}, ChainConfig{Default: ChainTron}.chainFamilyOf(TargetConfig{}))
+	assert.IsType(t, suiFamily{}, ChainConfig{Default: ChainSui}.chainFamilyOf(TargetConfig{}))
+
+	assert.EqualError(t, ChainConfig{Default: "bitcoin"}.Validate(), `unknown chain "bitcoin"`)
+	assert.EqualError(t, ChainConfig{Groups: map[string]string{"sol": "solano"}}.Validate(), `group "sol": unknown chain "solano"`)
+
+	// An unknown chain is reported as such, not as a nil healthchecker.
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+	assert.PanicsWithError(t, `unknown chain "bitcoin"`, func() {
+		NewHealthcheckManager(HealthcheckManagerConfig{
+			Targets: []TargetConfig{{Name: "Geth"}},
+			Chain:   ChainConfig{Default: "bitcoin"},
+		})
+	})
+}
+
+func TestClusterHeadByChain(t *testing.T) {
+	healthcheckManager := &HealthcheckManager{
+		healthcheckers: []Healthchecker{
+			&RPCHealthchecker{isHealthy: true, blockNumber: 19000000},
+			&RPCHealthchecker{isHealthy: true, blockNumber: 250000000},
+			&RPCHealthchecker{isHealthy: false, blockNumber: 19000100},
+		},
+		chains: []string{ChainEVM, ChainSolana, ChainEVM},
+	}
+
+	// The heights of different chains are not compared.
+	assert.Equal(t, uint64(19000000), healthcheckManager.highestBlockNumberOf(ChainEVM))
+	assert.Equal(t, uint64(250000000), healthcheckManager.highestBlockNumberOf(ChainSolana))
+	assert.Equal(t, uint64(0), healthcheckManager.highestBlockNumberOf(ChainSui))
+
+	// Nor are they by the requests routed to a group.
+	proxy := &Proxy{
+		config:             Config{Chain: ChainConfig{Groups: map[string]string{"sol": ChainSolana}}},
+		healthcheckManager: healthcheckManager,
+	}
+	assert.Equal(t, uint64(19000000), proxy.clusterHead(defaultTargetGroup)())
+	assert.Equal(t, uint64(250000000), proxy.clusterHead("sol")())
+}
+
+func TestChainExceptions(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	var received []string
+	fakeRPCServer := func(name string, body string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = append(received, name)
+			w.Write([]byte(body))
+		}))
+	}
+	laggingServer := fakeRPCServer("Lagging", `{"jsonrpc":"2.0","id":1,"error":{"code":-32002,"message":"Blockhash not found"}}`)
+	defer laggingServer.Close()
+	syncedServer := fakeRPCServer("Synced", `{"jsonrpc":"2.0","id":1,"result":{"value":true}}`)
+	defer syncedServer.Close()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Chain = ChainConfig{Default: ChainSolana}
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "Lagging",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: laggingServer.URL,
+				},
+			},
+		},
+		{
+			Name: "Synced",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: syncedServer.URL,
+				},
+			},
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+		Chain:   rpcGatewayConfig.Chain,
+	})
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+	assert.True(t, httpFailoverProxy.ServesWebsocketsOnNextPort())
+
+	// The Solana errors of a lagging node are retried on the other one.
+	for i := 0; i < 4; i++ {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"isBlockhashValid","params":["J7rBdM6AecPDEZp8aPq5iPSNKVkU5Q76F3oAV4eW5wsW"]}`))
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":{"value":true}}`, rr.Body.String())
+	}
+	assert.Contains(t, received, "Synced")
+}
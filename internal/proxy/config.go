@@ -2,18 +2,250 @@ package proxy
 
 import (
 	"time"
+
+	"github.com/0xProject/rpc-gateway/internal/metrics"
 )
 
 type HealthCheckConfig struct {
-	Interval         time.Duration `yaml:"interval"`
-	Timeout          time.Duration `yaml:"timeout"`
-	FailureThreshold uint          `yaml:"failureThreshold"`
-	SuccessThreshold uint          `yaml:"successThreshold"`
+	Interval         time.Duration      `yaml:"interval"`
+	Timeout          time.Duration      `yaml:"timeout"`
+	FailureThreshold uint               `yaml:"failureThreshold"`
+	SuccessThreshold uint               `yaml:"successThreshold"`
+	Capabilities     CapabilitiesConfig `yaml:"capabilities"`
+	SLO              SLOConfig          `yaml:"slo"`
+	Probes           []ProbeConfig      `yaml:"probes"`
+	Solana           SolanaHealthConfig `yaml:"solana"`
+	Tron             TronHealthConfig   `yaml:"tron"`
+	Sui              SuiHealthConfig    `yaml:"sui"`
+	Readiness        ReadinessConfig    `yaml:"readiness"`
+}
+
+// ReadinessConfig sets when the gateway reports itself ready on /readyz.
+type ReadinessConfig struct {
+	// How many targets must be ready, defaults to 1.
+	MinReadyTargets uint `yaml:"minReadyTargets"`
+	// How many blocks a ready target can be behind the head of the targets
+	// of its chain, unlimited when 0.
+	MaxBlockLag uint64 `yaml:"maxBlockLag"`
+	// Whether the gateway is not ready until every target completed its
+	// first round of health checks, defaults to true.
+	WaitForFirstRound *bool `yaml:"waitForFirstRound"`
+	// How long to wait for the first round at most, defaults to 1m. The
+	// targets not checked by then are not waited for anymore.
+	FirstRoundTimeout time.Duration `yaml:"firstRoundTimeout"`
+}
+
+// SolanaHealthConfig holds the limits of the Solana health profile.
+type SolanaHealthConfig struct {
+	// How many slots a node can be behind the highest slot of the healthy
+	// nodes, defaults to 150.
+	MaxSlotLag uint64 `yaml:"maxSlotLag"`
+	// How long the latest blockhash can stay the same, defaults to 1m.
+	MaxBlockhashAge time.Duration `yaml:"maxBlockhashAge"`
+}
+
+// TronHealthConfig holds the limits of the Tron health profile.
+type TronHealthConfig struct {
+	// How many blocks a node can be behind the highest block of the healthy
+	// nodes, defaults to 20.
+	MaxBlockLag uint64 `yaml:"maxBlockLag"`
+	// How old the latest block of a node can be, defaults to 1m.
+	MaxBlockAge time.Duration `yaml:"maxBlockAge"`
+}
+
+// SuiHealthConfig holds the limits of the Sui health profile.
+type SuiHealthConfig struct {
+	// How many checkpoints a node can be behind the highest checkpoint of
+	// the healthy nodes, defaults to 240.
+	MaxCheckpointLag uint64 `yaml:"maxCheckpointLag"`
+	// How old the latest checkpoint of a node can be, defaults to 1m.
+	MaxCheckpointAge time.Duration `yaml:"maxCheckpointAge"`
+}
+
+// ProbeConfig is a JSON-RPC call made on every health check on top of the
+// built-in ones.
+type ProbeConfig struct {
+	// Identifies the probe in the metrics, defaults to the method.
+	Name   string        `yaml:"name"`
+	Method string        `yaml:"method"`
+	Params []interface{} `yaml:"params"`
+	Expect ProbeExpect   `yaml:"expect"`
+	// How many failures a failed probe counts for towards the failure
+	// threshold, defaults to 1. Set it to 0 to only report the probe.
+	Weight *uint `yaml:"weight"`
+}
+
+// ProbeExpect is the assertion on the result of a probe. A probe passes when
+// the call succeeds and the result matches every field set.
+type ProbeExpect struct {
+	// JSON type of the result: "string", "number", "bool", "object",
+	// "array" or "null".
+	Type string `yaml:"type"`
+	// The result must be equal to the value, e.g. false for eth_syncing.
+	Equals interface{} `yaml:"equals"`
+	// Numbers and hex quantities must be at least Min, e.g. 1 for
+	// net_peerCount.
+	Min *float64 `yaml:"min"`
+}
+
+// SLOConfig controls the latency and error rate tracking of the targets.
+type SLOConfig struct {
+	// Length of the rolling window, defaults to 5m.
+	Window time.Duration `yaml:"window"`
+}
+
+// CapabilitiesConfig lists the methods probed on every target to find out
+// which ones it supports.
+type CapabilitiesConfig struct {
+	Methods []string `yaml:"methods"`
+	// How often to probe, defaults to 10m.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// EmptyResultFailoverConfig lists the methods for which a null/empty result
+// is retried on a different target before being returned to the client.
+type EmptyResultFailoverConfig struct {
+	Methods []string `yaml:"methods"`
+	// How many other targets to try at most, defaults to 1.
+	Retries uint `yaml:"retries"`
+}
+
+// BlockPinningConfig keeps consecutive reads consistent across targets.
+type BlockPinningConfig struct {
+	// Route calls made against an explicit block number only to targets
+	// that have reached that block.
+	Enabled bool `yaml:"enabled"`
+	// Rewrite the `latest` block tag to a block number. "target" pins it to
+	// the height of the chosen target, "cluster" to the height every healthy
+	// target has reached. Empty leaves the tag as is.
+	Latest string `yaml:"latest"`
+}
+
+// StickinessConfig routes every request of a client to the same target.
+type StickinessConfig struct {
+	// What identifies a client: "ip", "apiKey" (X-Api-Key header or apiKey
+	// query param) or "header". Empty disables stickiness.
+	Key string `yaml:"key"`
+	// The header identifying a client when Key is "header".
+	Header string `yaml:"header"`
+	// How long an idle assignment is listed in the admin API, defaults to 10m.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// BroadcastConfig sends raw transactions to many targets at once.
+type BroadcastConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Names of the targets to broadcast to, all healthy targets when empty.
+	Targets []string `yaml:"targets"`
+}
+
+// ConsensusConfig sends reads to many targets and answers with the result
+// a quorum of them agree on.
+type ConsensusConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Methods always served in consensus mode.
+	Methods []string `yaml:"methods"`
+	// The header clients set to "true" to ask for consensus per request,
+	// defaults to X-Rpc-Consensus.
+	Header string `yaml:"header"`
+	// How many healthy targets the call is sent to, defaults to 3.
+	Targets uint `yaml:"targets"`
+	// How many of them must agree, defaults to a majority of Targets.
+	Quorum uint `yaml:"quorum"`
+}
+
+// ShadowConfig controls the mirroring of requests to the shadow targets.
+type ShadowConfig struct {
+	// Fraction of the requests mirrored, defaults to 0.1.
+	SampleRate float64 `yaml:"sampleRate"`
+	// How long to wait for a shadow target, defaults to 30s.
+	Timeout time.Duration `yaml:"timeout"`
+	// Responses larger than MaxBodySize bytes are not compared, defaults
+	// to 1MiB.
+	MaxBodySize int `yaml:"maxBodySize"`
+	// Methods mirrored, wildcard patterns like the firewall ones. Defaults
+	// to the common read-only methods, the ones with side effects or
+	// node-local state must not be sent twice.
+	Methods []string `yaml:"methods"`
+}
+
+// PrivateTransactionsConfig routes transaction submissions to a group of
+// private (MEV protected) targets instead of the public ones.
+type PrivateTransactionsConfig struct {
+	// The target group receiving private submissions, empty disables it.
+	Group string `yaml:"group"`
+	// Methods routed to the group, defaults to eth_sendRawTransaction and
+	// eth_sendPrivateTransaction.
+	Methods []string `yaml:"methods"`
+	// Either "private" or "public", defaults to "private".
+	Mode string `yaml:"mode"`
+	// The header clients set to choose a mode per request, defaults to
+	// X-Rpc-Tx-Mode.
+	ModeHeader string `yaml:"modeHeader"`
+	// What to do when no private target is healthy: "public" submits to the
+	// public targets, "none" fails the request. Defaults to "none".
+	Fallback string `yaml:"fallback"`
+}
+
+// GetLogsConfig controls the splitting of eth_getLogs requests wider than
+// what the targets accept.
+type GetLogsConfig struct {
+	Split bool `yaml:"split"`
+	// How many chunks run in parallel, defaults to 4.
+	Concurrency uint `yaml:"concurrency"`
+	// How many chunks a request may be split into, wider requests are
+	// rejected. Defaults to 100.
+	MaxChunks uint64 `yaml:"maxChunks"`
+}
+
+// ArchiveConfig controls the routing of historical state requests.
+type ArchiveConfig struct {
+	// Calls made against a block more than Depth blocks behind the head are
+	// routed to archive targets, defaults to 128.
+	Depth uint64 `yaml:"depth"`
+}
+
+// AccessLogConfig writes a JSON line per client request with the upstream
+// attempts made for it.
+type AccessLogConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Written to stdout when empty.
+	File string `yaml:"file"`
+	// Rotation of the file: size in megabytes, number and age in days of
+	// the rotated files kept.
+	MaxSizeMB  int  `yaml:"maxSizeMB"`
+	MaxBackups int  `yaml:"maxBackups"`
+	MaxAgeDays int  `yaml:"maxAgeDays"`
+	Compress   bool `yaml:"compress"`
+	// Fraction of the successful requests logged, defaults to 1. Failed
+	// requests are always logged.
+	SampleRate float64 `yaml:"sampleRate"`
+	// Include the request and response bodies, truncated to MaxBodySize
+	// bytes (defaults to 1024).
+	Bodies      bool `yaml:"bodies"`
+	MaxBodySize int  `yaml:"maxBodySize"`
 }
 
 type ProxyConfig struct { // nolint:revive
-	Port            string        `yaml:"port"`
-	UpstreamTimeout time.Duration `yaml:"upstreamTimeout"`
+	Port                string                    `yaml:"port"`
+	UpstreamTimeout     time.Duration             `yaml:"upstreamTimeout"`
+	EmptyResultFailover EmptyResultFailoverConfig `yaml:"emptyResultFailover"`
+	BlockPinning        BlockPinningConfig        `yaml:"blockPinning"`
+	Stickiness          StickinessConfig          `yaml:"stickiness"`
+	Broadcast           BroadcastConfig           `yaml:"broadcast"`
+	Consensus           ConsensusConfig           `yaml:"consensus"`
+	Shadow              ShadowConfig              `yaml:"shadow"`
+	PrivateTransactions PrivateTransactionsConfig `yaml:"privateTransactions"`
+	Firewall            FirewallConfig            `yaml:"firewall"`
+	GetLogs             GetLogsConfig             `yaml:"getLogs"`
+	Archive             ArchiveConfig             `yaml:"archive"`
+	AccessLog           AccessLogConfig           `yaml:"accessLog"`
+	// How long /healthz reports the gateway unhealthy before it stops
+	// accepting connections on shutdown, so that load balancers notice.
+	ShutdownDelay time.Duration `yaml:"shutdownDelay"`
+	// How long the requests and websockets in flight are waited for on
+	// shutdown, defaults to 30s.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
 }
 
 type TargetConnectionHTTP struct {
@@ -36,10 +268,39 @@ type Exception struct {
 	Message string `yaml:"message"`
 }
 
+// FirewallConfig restricts the JSON-RPC methods that can be called. Patterns
+// support `*` wildcards, e.g. "debug_*".
+type FirewallConfig struct {
+	// When not empty, only the matching methods are allowed.
+	Allow []string `yaml:"allow"`
+	// The matching methods are rejected, takes precedence over Allow.
+	Deny []string `yaml:"deny"`
+}
+
+// GetLogsLimits are the eth_getLogs limits enforced by a target, zero means
+// unlimited.
+type GetLogsLimits struct {
+	MaxBlockRange uint64 `yaml:"maxBlockRange"`
+	MaxResults    uint64 `yaml:"maxResults"`
+}
+
 type TargetConfig struct {
 	Name       string                 `yaml:"name"`
 	Connection TargetConfigConnection `yaml:"connection"`
 	IsDisabled bool                   `yaml:"disabled"`
+	// Targets of a named group only receive the requests routed to the
+	// group, e.g. private transaction submissions. The default group serves
+	// everything else.
+	Group string `yaml:"group"`
+	// Methods this target accepts, on top of the global firewall.
+	Firewall FirewallConfig `yaml:"firewall"`
+	GetLogs  GetLogsLimits  `yaml:"getLogs"`
+	// Whether the target serves historical state. Probed by the
+	// healthchecker when not set.
+	Archive *bool `yaml:"archive"`
+	// Shadow targets never serve clients, they get a copy of a sample of
+	// the requests of their group to compare them with the other targets.
+	Shadow bool `yaml:"shadow"`
 }
 
 // This struct is temporary. It's about to keep the input interface clean and simple.
@@ -48,5 +309,8 @@ type Config struct {
 	Targets      []TargetConfig
 	HealthChecks HealthCheckConfig
 	Exceptions   []Exception
-	Solana       bool
+	// Chain families of the targets.
+	Chain ChainConfig
+	// Registry the metrics are created in, the default registerer when nil.
+	Metrics *metrics.Registry
 }
@@ -0,0 +1,294 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultConsensusHeader  = "X-Rpc-Consensus"
+	defaultConsensusTargets = 3
+
+	// JSON-RPC error code of the calls the targets did not agree on.
+	noQuorumErrorCode = -32099
+)
+
+type consensusResult struct {
+	target *HTTPTarget
+	body   []byte
+	// the normalized answer, empty when the call failed.
+	key    string
+	reason string
+}
+
+// isConsensus reports whether request is served in consensus mode, because
+// of its method or because the client asked for it with the header.
+func (h *Proxy) isConsensus(r *http.Request, request *RPCRequest) bool {
+	config := h.config.Proxy.Consensus
+	if !config.Enabled {
+		return false
+	}
+	if slices.Contains(config.Methods, request.Method) {
+		return true
+	}
+
+	header := config.Header
+	if header == "" {
+		header = defaultConsensusHeader
+	}
+	enabled, _ := strconv.ParseBool(r.Header.Get(header))
+
+	return enabled
+}
+
+// consensusQuorum returns how many targets are asked and how many of them
+// must agree.
+func (c ConsensusConfig) consensusQuorum() (int, int) {
+	targets := int(c.Targets)
+	if targets == 0 {
+		targets = defaultConsensusTargets
+	}
+	quorum := int(c.Quorum)
+	if quorum == 0 {
+		quorum = targets/2 + 1
+	}
+
+	return targets, quorum
+}
+
+// Validate reports a quorum the targets asked can never reach.
+func (c ConsensusConfig) Validate() error {
+	targets, quorum := c.consensusQuorum()
+	if quorum > targets {
+		return fmt.Errorf("consensus: quorum of %d exceeds the %d targets asked", quorum, targets)
+	}
+
+	return nil
+}
+
+// consensusTargets returns up to n targets picked at random from the first
+// group request can be routed to that has any, filtered like a single call.
+func (h *Proxy) consensusTargets(r *http.Request, n int) []*HTTPTarget {
+	for _, group := range h.targetGroups(r) {
+		targets := h.routableTargets(r, group)
+		if len(targets) == 0 {
+			continue
+		}
+
+		rand.Shuffle(len(targets), func(i, j int) { targets[i], targets[j] = targets[j], targets[i] })
+		if len(targets) > n {
+			targets = targets[:n]
+		}
+
+		return targets
+	}
+
+	return nil
+}
+
+// serveConsensus sends the call to many targets in parallel and responds
+// with the answer a quorum of them agree on, as soon as they do. Otherwise
+// it responds with a JSON-RPC error and logs what every target answered.
+func (h *Proxy) serveConsensus(w http.ResponseWriter, r *http.Request, request *RPCRequest) {
+	n, quorum := h.config.Proxy.Consensus.consensusQuorum()
+	targets := h.consensusTargets(r, n)
+	if len(targets) < quorum {
+		h.metricConsensus.Inc("no_quorum")
+		writeConsensusError(w, request, fmt.Sprintf("not enough healthy targets for a quorum: %d of %d", len(targets), quorum))
+		return
+	}
+
+	body := GetRequestBodyFromContext(r).Raw
+
+	start := time.Now()
+	results := make(chan consensusResult, len(targets))
+	for _, target := range targets {
+		go func(target *HTTPTarget) {
+			results <- h.callForConsensus(r, target, body)
+		}(target)
+	}
+
+	collected := make([]consensusResult, 0, len(targets))
+	votes := map[string]int{}
+	var agreed string
+	for len(collected) < len(targets) && agreed == "" {
+		result := <-results
+		collected = append(collected, result)
+		if result.key == "" {
+			continue
+		}
+		if votes[result.key]++; votes[result.key] >= quorum {
+			agreed = result.key
+		}
+	}
+
+	if agreed == "" {
+		h.metricConsensus.Inc("no_quorum")
+		h.recordDisagreements(request, collected, majorityKey(votes))
+		writeConsensusError(w, request, fmt.Sprintf("no quorum: %d targets must agree", quorum))
+		return
+	}
+
+	h.metricConsensus.Inc("agreed")
+	h.recordDisagreements(request, collected, agreed)
+
+	var agreeing []string
+	for _, result := range collected {
+		if result.key == agreed {
+			agreeing = append(agreeing, result.target.Config.Name)
+		}
+	}
+	index := slices.IndexFunc(collected, func(result consensusResult) bool { return result.key == agreed })
+	w.Header().Set(providerHeader, strings.Join(agreeing, ", "))
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(collected[index].body) // nolint:errcheck
+	h.observeResponseTime(r, agreeing[0], time.Since(start))
+}
+
+// callForConsensus makes the call on a single target and normalizes the
+// answer.
+func (h *Proxy) callForConsensus(r *http.Request, target *HTTPTarget, body []byte) consensusResult {
+	result := consensusResult{target: target}
+
+	data, err := target.Call(r.Context(), body)
+	result.body = data
+	if err != nil {
+		result.reason = err.Error()
+		return result
+	}
+
+	if message, ok := matchException(string(data), h.exceptionsOf(target.Config)); ok {
+		result.reason = message
+		return result
+	}
+
+	response, err := ParseRPCResponse(data)
+	if err != nil {
+		result.reason = err.Error()
+		return result
+	}
+
+	result.key, err = normalizeResponse(response)
+	if err != nil {
+		result.reason = err.Error()
+	}
+
+	return result
+}
+
+// normalizeResponse encodes the answer of a response so that equal answers
+// of different clients compare equal: the keys of the objects are sorted
+// and the hex strings lowercased, e.g. checksummed addresses. Errors only
+// compare equal with the same code, message and data: the codes are too
+// coarse, -32000 stands for most failures of a node.
+func normalizeResponse(response *RPCResponse) (string, error) {
+	if response.Error != nil {
+		data, err := normalizeJSON(response.Error.Data)
+		if err != nil {
+			return "", err
+		}
+		message := strconv.Quote(response.Error.Message)
+
+		return "error:" + strconv.Itoa(response.Error.Code) + ":" + message + ":" + data, nil
+	}
+
+	result, err := normalizeJSON(response.Result)
+	if err != nil {
+		return "", err
+	}
+
+	return "result:" + result, nil
+}
+
+func normalizeJSON(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return "", err
+	}
+
+	normalized, err := json.Marshal(lowercaseHex(value))
+	if err != nil {
+		return "", err
+	}
+
+	return string(normalized), nil
+}
+
+func lowercaseHex(value interface{}) interface{} {
+	switch value := value.(type) {
+	case string:
+		if strings.HasPrefix(value, "0x") || strings.HasPrefix(value, "0X") {
+			return strings.ToLower(value)
+		}
+	case []interface{}:
+		for i := range value {
+			value[i] = lowercaseHex(value[i])
+		}
+	case map[string]interface{}:
+		for key := range value {
+			value[key] = lowercaseHex(value[key])
+		}
+	}
+
+	return value
+}
+
+// majorityKey returns the most common answer, the lowest one on a tie so
+// that the choice is stable.
+func majorityKey(votes map[string]int) string {
+	var majority string
+	for key, count := range votes {
+		if count > votes[majority] || (count == votes[majority] && key < majority) {
+			majority = key
+		}
+	}
+
+	return majority
+}
+
+// recordDisagreements counts and logs the targets which did not give the
+// expected answer.
+func (h *Proxy) recordDisagreements(request *RPCRequest, results []consensusResult, expected string) {
+	var agreed, disagreed []string
+	for _, result := range results {
+		name := result.target.Config.Name
+		switch {
+		case result.key == "":
+			h.metricConsensusDisagreements.Inc(name, "failed")
+			disagreed = append(disagreed, name+": "+result.reason)
+		case result.key != expected:
+			h.metricConsensusDisagreements.Inc(name, "mismatch")
+			disagreed = append(disagreed, name+": "+result.key)
+		default:
+			agreed = append(agreed, name)
+		}
+	}
+	if len(disagreed) == 0 {
+		return
+	}
+
+	zap.L().Warn("consensus disagreement",
+		zap.String("method", request.Method),
+		zap.Strings("agreedBy", agreed),
+		zap.Strings("disagreedBy", disagreed))
+}
+
+func writeConsensusError(w http.ResponseWriter, request *RPCRequest, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(NewRPCError(request.ID, noQuorumErrorCode, message)) // nolint:errcheck
+}
@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func createConsensusProxy(t *testing.T, consensus ConsensusConfig, responses map[string]string) *Proxy {
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Proxy.Consensus = consensus
+	for _, name := range []string{"Server1", "Server2", "Server3"} {
+		response := responses[name]
+		fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if response == "" {
+				http.Error(w, "Bad Gateway", http.StatusBadGateway)
+				return
+			}
+			w.Write([]byte(response))
+		}))
+		t.Cleanup(fakeRPCServer.Close)
+
+		rpcGatewayConfig.Targets = append(rpcGatewayConfig.Targets, TargetConfig{
+			Name: name,
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPCServer.URL,
+				},
+			},
+		})
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	markHealthy(healthcheckManager)
+
+	return NewProxy(rpcGatewayConfig, healthcheckManager)
+}
+
+func serveBalanceRequest(t *testing.T, httpFailoverProxy *Proxy, header string) *httptest.ResponseRecorder {
+	requestBody := bytes.NewBufferString(`{"jsonrpc":"2.0","id":3,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000000000","finalized"]}`)
+	req, err := http.NewRequest("POST", "/", requestBody)
+	assert.Nil(t, err)
+	if header != "" {
+		req.Header.Set("X-Rpc-Consensus", header)
+	}
+
+	rr := httptest.NewRecorder()
+	httpFailoverProxy.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestConsensusQuorum(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	httpFailoverProxy := createConsensusProxy(t, ConsensusConfig{Enabled: true, Methods: []string{"eth_getBalance"}}, map[string]string{
+		"Server1": `{"jsonrpc":"2.0","id":3,"result":"0xDE0B6B3A7640000"}`,
+		"Server2": `{"id":3,"jsonrpc":"2.0","result":"0xde0b6b3a7640000"}`,
+		"Server3": `{"jsonrpc":"2.0","id":3,"result":"0x0"}`,
+	})
+
+	rr := serveBalanceRequest(t, httpFailoverProxy, "")
+
+	// The two targets agreeing once normalized make the quorum.
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Header().Get("X-Rpc-Provider"), "Server1")
+	assert.Contains(t, rr.Header().Get("X-Rpc-Provider"), "Server2")
+	assert.NotContains(t, rr.Header().Get("X-Rpc-Provider"), "Server3")
+	response, err := ParseRPCResponse(rr.Body.Bytes())
+	assert.Nil(t, err)
+	assert.Nil(t, response.Error)
+}
+
+func TestConsensusNoQuorum(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	httpFailoverProxy := createConsensusProxy(t, ConsensusConfig{Enabled: true}, map[string]string{
+		"Server1": `{"jsonrpc":"2.0","id":3,"result":"0x1"}`,
+		"Server2": `{"jsonrpc":"2.0","id":3,"result":"0x2"}`,
+	})
+
+	// The header asks for consensus, the targets don't agree.
+	rr := serveBalanceRequest(t, httpFailoverProxy, "true")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	response, err := ParseRPCResponse(rr.Body.Bytes())
+	assert.Nil(t, err)
+	assert.NotNil(t, response.Error)
+	assert.Equal(t, noQuorumErrorCode, response.Error.Code)
+	assert.Equal(t, `3`, string(response.ID))
+
+	// Without the header the call goes to a single target.
+	rr = serveBalanceRequest(t, httpFailoverProxy, "")
+	response, err = ParseRPCResponse(rr.Body.Bytes())
+	assert.Nil(t, err)
+	assert.Nil(t, response.Error)
+}
+
+func TestConsensusTargetsFirewall(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	httpFailoverProxy := createConsensusProxy(t, ConsensusConfig{Enabled: true, Quorum: 2}, map[string]string{
+		"Server1": `{"jsonrpc":"2.0","id":3,"result":"0x1"}`,
+		"Server2": `{"jsonrpc":"2.0","id":3,"result":"0x1"}`,
+		"Server3": `{"jsonrpc":"2.0","id":3,"result":"0x2"}`,
+	})
+	httpFailoverProxy.targets[2].Config.Firewall = FirewallConfig{Deny: []string{"eth_getBalance"}}
+
+	// The target whose firewall denies the method is never asked.
+	for i := 0; i < 8; i++ {
+		rr := serveBalanceRequest(t, httpFailoverProxy, "true")
+		assert.ElementsMatch(t, []string{"Server1", "Server2"}, strings.Split(rr.Header().Get("X-Rpc-Provider"), ", "))
+	}
+
+	httpFailoverProxy.targets[1].Config.Firewall = FirewallConfig{Deny: []string{"eth_*"}}
+	rr := serveBalanceRequest(t, httpFailoverProxy, "true")
+	response, err := ParseRPCResponse(rr.Body.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, noQuorumErrorCode, response.Error.Code)
+	assert.Equal(t, "not enough healthy targets for a quorum: 1 of 2", response.Error.Message)
+}
+
+func TestNormalizeResponse(t *testing.T) {
+	for _, tc := range []struct {
+		a, b  string
+		equal bool
+	}{
+		{`{"result":{"b":1,"a":"0xAB"}}`, `{"result":{"a":"0xab","b":1}}`, true},
+		{`{"result":"Hello"}`, `{"result":"hello"}`, false},
+		{`{"result":123456789012345678901234567890}`, `{"result":123456789012345678901234567891}`, false},
+		{`{"error":{"code":3,"message":"execution reverted","data":"0x08C3"}}`, `{"error":{"code":3,"message":"execution reverted","data":"0x08c3"}}`, true},
+		{`{"error":{"code":-32000,"message":"header not found"}}`, `{"error":{"code":-32000,"message":"missing trie node"}}`, false},
+		{`{"error":{"code":-32000,"message":"header not found"}}`, `{"result":null}`, false},
+	} {
+		a, err := ParseRPCResponse([]byte(tc.a))
+		assert.Nil(t, err)
+		b, err := ParseRPCResponse([]byte(tc.b))
+		assert.Nil(t, err)
+
+		keyA, err := normalizeResponse(a)
+		assert.Nil(t, err)
+		keyB, err := normalizeResponse(b)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.equal, keyA == keyB, "%s and %s", tc.a, tc.b)
+	}
+}
+
+func TestConsensusConfigValidate(t *testing.T) {
+	assert.Nil(t, ConsensusConfig{}.Validate())
+	assert.Nil(t, ConsensusConfig{Targets: 5}.Validate())
+	assert.Nil(t, ConsensusConfig{Targets: 2, Quorum: 2}.Validate())
+	assert.EqualError(t, ConsensusConfig{Targets: 2, Quorum: 3}.Validate(), "consensus: quorum of 3 exceeds the 2 targets asked")
+	assert.EqualError(t, ConsensusConfig{Quorum: 4}.Validate(), "consensus: quorum of 4 exceeds the 3 targets asked")
+}
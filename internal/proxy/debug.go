@@ -0,0 +1,157 @@
+package proxy
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultDebugRuleDuration = 5 * time.Minute
+	maxDebugBodySize         = 4096
+)
+
+// DebugRule turns on verbose logging of the requests routed to Target and
+// calling Method until it expires. An empty Target or Method matches any.
+type DebugRule struct {
+	Target string    `json:"target,omitempty"`
+	Method string    `json:"method,omitempty"`
+	Until  time.Time `json:"until"`
+}
+
+func (d DebugRule) matches(target string, methods []string) bool {
+	return (d.Target == "" || d.Target == target) &&
+		(d.Method == "" || slices.Contains(methods, d.Method))
+}
+
+type debugRules struct {
+	mu    sync.Mutex
+	rules []DebugRule
+}
+
+// active drops the expired rules and returns the others.
+func (d *debugRules) active() []DebugRule {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.rules = slices.DeleteFunc(d.rules, func(rule DebugRule) bool {
+		return now.After(rule.Until)
+	})
+
+	return slices.Clone(d.rules)
+}
+
+// AddDebugRule enables verbose logging for target and method for duration,
+// 5 minutes when not set.
+func (h *Proxy) AddDebugRule(target, method string, duration time.Duration) DebugRule {
+	if duration <= 0 {
+		duration = defaultDebugRuleDuration
+	}
+	rule := DebugRule{Target: target, Method: method, Until: time.Now().Add(duration)}
+
+	h.debugRules.mu.Lock()
+	defer h.debugRules.mu.Unlock()
+	h.debugRules.rules = append(h.debugRules.rules, rule)
+
+	return rule
+}
+
+// GetDebugRules returns the rules that did not expire yet.
+func (h *Proxy) GetDebugRules() []DebugRule {
+	return h.debugRules.active()
+}
+
+// ClearDebugRules turns verbose logging off.
+func (h *Proxy) ClearDebugRules() {
+	h.debugRules.mu.Lock()
+	defer h.debugRules.mu.Unlock()
+	h.debugRules.rules = nil
+}
+
+// debugLogger returns a logger bypassing the log level when a debug rule
+// matches the request routed to target, nil otherwise.
+func (h *Proxy) debugLogger(r *http.Request, target string) *zap.Logger {
+	rules := h.debugRules.active()
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var methods []string
+	if body := GetRequestBodyFromContext(r); body != nil {
+		for _, request := range body.Requests {
+			methods = append(methods, request.Method)
+		}
+	}
+
+	for _, rule := range rules {
+		if rule.matches(target, methods) {
+			logger := zap.New(forcedCore{zap.L().Core()})
+			if history := GetRequestHistoryFromContext(r); history != nil {
+				logger = logger.With(zap.String("requestId", history.ID))
+			}
+
+			return logger.With(zap.String("provider", target), zap.Strings("methods", methods))
+		}
+	}
+
+	return nil
+}
+
+// logDebugRequest logs the request forwarded to target when a debug rule
+// matches it.
+func (h *Proxy) logDebugRequest(r *http.Request, target string) {
+	logger := h.debugLogger(r, target)
+	if logger == nil {
+		return
+	}
+
+	var body []byte
+	if decoded := GetRequestBodyFromContext(r); decoded != nil {
+		body = decoded.Raw
+	}
+	logger.Debug("upstream request",
+		zap.String("path", r.URL.Path),
+		zap.String("body", truncate(body, maxDebugBodySize)),
+	)
+}
+
+// logDebugResponse logs the response of target when a debug rule matches the
+// request.
+func (h *Proxy) logDebugResponse(resp *http.Response, config TargetConfig) {
+	logger := h.debugLogger(resp.Request, config.Name)
+	if logger == nil {
+		return
+	}
+
+	body, err := getResponseBody(resp, config)
+	if err != nil {
+		logger.Debug("cannot read upstream response", zap.Error(err))
+		return
+	}
+	logger.Debug("upstream response",
+		zap.Int("status", resp.StatusCode),
+		zap.String("body", truncate([]byte(body), maxDebugBodySize)),
+	)
+}
+
+// forcedCore writes every entry whatever the level of the wrapped core.
+type forcedCore struct {
+	zapcore.Core
+}
+
+func (c forcedCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c forcedCore) With(fields []zapcore.Field) zapcore.Core {
+	return forcedCore{c.Core.With(fields)}
+}
+
+func (c forcedCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checked.AddCore(entry, c)
+}
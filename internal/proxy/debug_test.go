@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDebugRules(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	t.Cleanup(zap.ReplaceGlobals(zap.New(core)))
+
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer fakeRPCServer.Close()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "Server1",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPCServer.URL,
+				},
+			},
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	serve := func(method string) {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"`+method+`"}`))
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	httpFailoverProxy.AddDebugRule("Server1", "eth_chainId", time.Minute)
+	rule := httpFailoverProxy.AddDebugRule("", "eth_call", 0)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), rule.Until, time.Second)
+	assert.Len(t, httpFailoverProxy.GetDebugRules(), 2)
+
+	// Logged despite the warn level.
+	serve("eth_chainId")
+	entries := logs.TakeAll()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "upstream request", entries[0].Message)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"method":"eth_chainId"}`, entries[0].ContextMap()["body"])
+	assert.Equal(t, "upstream response", entries[1].Message)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`, entries[1].ContextMap()["body"])
+	assert.Equal(t, "Server1", entries[1].ContextMap()["provider"])
+
+	serve("eth_blockNumber")
+	assert.Empty(t, logs.TakeAll())
+
+	// Rules expire.
+	httpFailoverProxy.ClearDebugRules()
+	httpFailoverProxy.AddDebugRule("", "eth_chainId", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	assert.Empty(t, httpFailoverProxy.GetDebugRules())
+	serve("eth_chainId")
+	assert.Empty(t, logs.TakeAll())
+}
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// evmFamily serves Ethereum and the chains compatible with its JSON-RPC API.
+type evmFamily struct{}
+
+func (evmFamily) HeadHeight(ctx context.Context, h *RPCHealthchecker) (uint64, error) {
+	return h.checkBlockNumber(ctx)
+}
+
+// LivenessChecks makes an `eth_call`, the block number can be cached or
+// routed to a different service on the provider's side.
+func (evmFamily) LivenessChecks(h *RPCHealthchecker) []probeCheck {
+	return []probeCheck{
+		{name: "gasLeft", weight: 1, run: h.checkAndSetGasLimit},
+	}
+}
+
+func (evmFamily) Exceptions() []Exception {
+	return nil
+}
+
+func (evmFamily) WebsocketOnNextPort() bool {
+	return false
+}
+
+// ProbeArchive requests a balance at block 1. Archive nodes serve it, full
+// nodes only keep the state of the recent blocks and answer with an error
+// such as "missing trie node". Any other error, e.g. a rate limit, tells
+// nothing and is returned.
+func (evmFamily) ProbeArchive(ctx context.Context, h *RPCHealthchecker) (bool, error) {
+	var balance hexutil.Big
+	err := h.client.CallContext(ctx, &balance, "eth_getBalance", common.Address{}, "0x1")
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && isMissingStateError(rpcErr.Error()) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// isMissingStateError matches the errors returned by nodes that pruned the
+// state of old blocks.
+func isMissingStateError(message string) bool {
+	message = strings.ToLower(message)
+	for _, missing := range []string{
+		"missing trie node",
+		"state is not available",
+		"state not available",
+		"historical state",
+		"missing state",
+		"state pruned",
+	} {
+		if strings.Contains(message, missing) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ProbeHeads requests the latest, safe and finalized blocks. Nodes of the
+// chains without finality tags answer with an error or no block for them,
+// which leaves the tags out.
+func (evmFamily) ProbeHeads(ctx context.Context, h *RPCHealthchecker) (map[string]BlockHead, error) {
+	heads := map[string]BlockHead{}
+	for _, tag := range []string{BlockTagLatest, BlockTagSafe, BlockTagFinalized} {
+		var block *struct {
+			Number hexutil.Uint64 `json:"number"`
+			Hash   string         `json:"hash"`
+		}
+		err := h.client.CallContext(ctx, &block, "eth_getBlockByNumber", tag, false)
+
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) || (err == nil && block == nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		heads[tag] = BlockHead{Number: uint64(block.Number), Hash: block.Hash}
+	}
+	if _, ok := heads[BlockTagLatest]; !ok {
+		return nil, errors.New("no latest block")
+	}
+
+	return heads, nil
+}
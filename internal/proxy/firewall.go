@@ -0,0 +1,167 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+
+	"go.uber.org/zap"
+)
+
+// Allows reports whether method passes the firewall. Deny rules take
+// precedence over allow rules, an empty allow list allows everything that
+// isn't denied.
+func (f FirewallConfig) Allows(method string) bool {
+	for _, pattern := range f.Deny {
+		if matchMethod(pattern, method) {
+			return false
+		}
+	}
+
+	if len(f.Allow) == 0 {
+		return true
+	}
+
+	for _, pattern := range f.Allow {
+		if matchMethod(pattern, method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f FirewallConfig) isEnabled() bool {
+	return len(f.Allow) > 0 || len(f.Deny) > 0
+}
+
+func matchMethod(pattern, method string) bool {
+	matched, err := path.Match(pattern, method)
+	if err != nil {
+		zap.L().Warn("invalid firewall pattern", zap.String("pattern", pattern), zap.Error(err))
+		return false
+	}
+
+	return matched
+}
+
+func (h *Proxy) isFirewallEnabled() bool {
+	if h.config.Proxy.Firewall.isEnabled() {
+		return true
+	}
+
+	for _, target := range h.targets {
+		if target.Config.Firewall.isEnabled() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isMethodAllowed reports whether method passes the global firewall and the
+// firewall of at least one target.
+func (h *Proxy) isMethodAllowed(method string) bool {
+	if !h.config.Proxy.Firewall.Allows(method) {
+		return false
+	}
+
+	for _, target := range h.targets {
+		if target.Config.Firewall.Allows(method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rejectBlockedMethods answers the request with a JSON-RPC error, before any
+// upstream is contacted, when one of its calls is blocked by the firewall.
+// It returns true when the request was rejected.
+func (h *Proxy) rejectBlockedMethods(w http.ResponseWriter, r *http.Request) bool {
+	// Websocket messages can't be inspected, the handshake is checked once
+	// the target is chosen.
+	if isWebsocketHandshake(r) || !h.isFirewallEnabled() {
+		return false
+	}
+
+	return h.enforceFirewall(w, r)
+}
+
+// rejectUninspectedHandshake rejects a websocket handshake routed to a
+// target without a websocket url when the firewall is enabled: the HTTP
+// proxy of the target would forward the body and the messages unchecked.
+func (h *Proxy) rejectUninspectedHandshake(w http.ResponseWriter, r *http.Request) bool {
+	if !h.isFirewallEnabled() {
+		return false
+	}
+
+	return h.enforceFirewall(w, r)
+}
+
+func (h *Proxy) enforceFirewall(w http.ResponseWriter, r *http.Request) bool {
+	body := GetRequestBodyFromContext(r)
+	if body == nil || len(body.Requests) == 0 {
+		// A body the firewall can't decode could still be understood by an
+		// upstream, so it's not forwarded.
+		writeRPCResponse(w, NewRPCError(nil, rpcErrorParse, "parse error"))
+		return true
+	}
+
+	blocked := false
+	for _, request := range body.Requests {
+		if !h.isMethodAllowed(request.Method) {
+			blocked = true
+			h.metricFirewallBlocked.Inc(h.methodName(request.Method))
+		}
+	}
+	if !blocked {
+		return false
+	}
+
+	responses := make([]json.RawMessage, 0, len(body.Requests))
+	for _, request := range body.Requests {
+		message := "method not allowed: " + request.Method
+		if h.isMethodAllowed(request.Method) {
+			message = "batch contains a method that is not allowed"
+		}
+		responses = append(responses, NewRPCError(request.ID, rpcErrorMethodNotFound, message))
+	}
+
+	if !body.IsBatch {
+		writeRPCResponse(w, responses[0])
+		return true
+	}
+
+	batch, err := json.Marshal(responses)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return true
+	}
+	writeRPCResponse(w, batch)
+
+	return true
+}
+
+// firewallFilter accepts the targets whose firewall allows every call of the
+// request.
+func (h *Proxy) firewallFilter(r *http.Request) TargetFilter {
+	body := GetRequestBodyFromContext(r)
+	if body == nil {
+		return nil
+	}
+
+	return func(index int, _ Healthchecker) bool {
+		for _, request := range body.Requests {
+			if !h.targets[index].Config.Firewall.Allows(request.Method) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func writeRPCResponse(w http.ResponseWriter, response []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response) // nolint:errcheck
+}
@@ -0,0 +1,163 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirewallAllows(t *testing.T) {
+	firewall := FirewallConfig{
+		Allow: []string{"eth_*", "net_version", "debug_*"},
+		Deny:  []string{"debug_setHead", "debug_traceBlock*"},
+	}
+
+	assert.True(t, firewall.Allows("eth_call"))
+	assert.True(t, firewall.Allows("net_version"))
+	assert.True(t, firewall.Allows("debug_traceTransaction"))
+	assert.False(t, firewall.Allows("debug_setHead"))
+	assert.False(t, firewall.Allows("debug_traceBlockByNumber"))
+	assert.False(t, firewall.Allows("admin_peers"))
+	assert.True(t, FirewallConfig{}.Allows("admin_peers"))
+}
+
+func TestFirewallRejectsBlockedMethods(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	received := &atomic.Int32{}
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer fakeRPCServer.Close()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Proxy.Firewall = FirewallConfig{
+		Deny: []string{"admin_*", "personal_*"},
+	}
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "Server1",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPCServer.URL,
+				},
+			},
+			Firewall: FirewallConfig{
+				Deny: []string{"debug_*"},
+			},
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	serve := func(body string) string {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(body))
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		return rr.Body.String()
+	}
+
+	assert.Equal(t,
+		`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not allowed: admin_addPeer"}}`,
+		serve(`{"jsonrpc":"2.0","id":1,"method":"admin_addPeer","params":[]}`))
+	// Not allowed by any target.
+	assert.Equal(t,
+		`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not allowed: debug_setHead"}}`,
+		serve(`{"jsonrpc":"2.0","id":1,"method":"debug_setHead","params":["0x1"]}`))
+	assert.Equal(t,
+		`[{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"batch contains a method that is not allowed"}},`+
+			`{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"method not allowed: personal_sign"}}]`,
+		serve(`[{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"},{"jsonrpc":"2.0","id":2,"method":"personal_sign"}]`))
+	assert.Equal(t, int32(0), received.Load())
+
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`, serve(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`))
+	assert.Equal(t, int32(1), received.Load())
+}
+
+func TestFirewallUpgradeHeader(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	prometheus.DefaultRegisterer = registry
+
+	received := &atomic.Int32{}
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer fakeRPCServer.Close()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Proxy.Firewall = FirewallConfig{
+		Allow: []string{"eth_*", "admin_*"},
+		Deny:  []string{"admin_*"},
+	}
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "Server1",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPCServer.URL,
+				},
+			},
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	markHealthy(healthcheckManager)
+
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	serve := func(body string, header http.Header) string {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(body))
+		assert.Nil(t, err)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+
+		return rr.Body.String()
+	}
+
+	// An Upgrade header alone doesn't make a websocket handshake, the body
+	// is checked.
+	assert.Equal(t,
+		`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not allowed: admin_addPeer"}}`,
+		serve(`{"jsonrpc":"2.0","id":1,"method":"admin_addPeer","params":[]}`, http.Header{"Upgrade": {"foo"}}))
+	assert.Equal(t,
+		`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not allowed: net_peerCount"}}`,
+		serve(`{"jsonrpc":"2.0","id":1,"method":"net_peerCount"}`, http.Header{"Upgrade": {"websocket"}}))
+	// Nor is a handshake let through to a target without a websocket url.
+	assert.Equal(t,
+		`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error"}}`,
+		serve(`{"jsonrpc":"2.0","id":1,"method":"admin_addPeer","params":[]}`,
+			http.Header{"Connection": {"Upgrade"}, "Upgrade": {"websocket"}}))
+	assert.Equal(t, int32(0), received.Load())
+
+	// Blocked calls are counted by method.
+	expected := `
+# HELP rpc_gateway_firewall_blocked_total Total number of calls rejected by the method firewall by method
+# TYPE rpc_gateway_firewall_blocked_total counter
+rpc_gateway_firewall_blocked_total{jsonrpc_method="admin_addPeer"} 1
+rpc_gateway_firewall_blocked_total{jsonrpc_method="net_peerCount"} 1
+`
+	assert.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "rpc_gateway_firewall_blocked_total"))
+}
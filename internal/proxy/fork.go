@@ -0,0 +1,174 @@
+package proxy
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// Block tags whose heads are recorded on every health check.
+const (
+	BlockTagLatest    = "latest"
+	BlockTagSafe      = "safe"
+	BlockTagFinalized = "finalized"
+)
+
+// BlockHead is the block a node returns for a block tag.
+type BlockHead struct {
+	Number uint64 `json:"number"`
+	Hash   string `json:"hash"`
+}
+
+// forkTracker is implemented by the families whose nodes return the hash of
+// the heads, which tells the nodes on a minority fork apart.
+type forkTracker interface {
+	// ProbeHeads fetches the heads of the block tags the node supports.
+	ProbeHeads(ctx context.Context, h *RPCHealthchecker) (map[string]BlockHead, error)
+}
+
+// checkAndSetHeads records the heads of the latest, safe and finalized
+// blocks of the node.
+func (h *RPCHealthchecker) checkAndSetHeads() {
+	tracker, ok := h.chain.(forkTracker)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	heads, err := tracker.ProbeHeads(ctx, h)
+	if err != nil {
+		zap.L().Warn("failed fetching the block heads", zap.Error(err), zap.String("rpcProvider", h.config.Name))
+		return
+	}
+	if finalized, ok := heads[BlockTagFinalized]; ok && h.metricFinalizedBlockNumber != nil {
+		h.metricFinalizedBlockNumber.Set(float64(finalized.Number), h.config.Name)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.heads = heads
+}
+
+// Heads returns the heads recorded on the last health check, by block tag.
+func (h *RPCHealthchecker) Heads() map[string]BlockHead {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	heads := make(map[string]BlockHead, len(h.heads))
+	for tag, head := range h.heads {
+		heads[tag] = head
+	}
+
+	return heads
+}
+
+// FinalizedBlockNumber returns the last finalized block of the node, 0 when
+// the node does not support the finalized tag.
+func (h *RPCHealthchecker) FinalizedBlockNumber() uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.heads[BlockTagFinalized].Number
+}
+
+func (h *RPCHealthchecker) IsDiverged() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.isDiverged
+}
+
+func (h *RPCHealthchecker) SetDiverged(diverged bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.isDiverged == diverged {
+		return
+	}
+	h.isDiverged = diverged
+	if diverged {
+		zap.L().Warn("RPC diverged from the majority fork", zap.String("name", h.config.Name))
+		return
+	}
+	zap.L().Info("RPC back on the majority fork", zap.String("name", h.config.Name))
+}
+
+// divergedTargets returns the indexes of the targets whose hash at a height
+// differs from the hash most of the targets of the same chain agree on.
+// Heights without a strict majority are not decided, two targets
+// disagreeing tell nothing.
+func divergedTargets(chains []string, heads []map[string]BlockHead) map[int]bool {
+	type vote struct {
+		chain  string
+		number uint64
+	}
+
+	// hashes of every target by chain and height, the tags of a target may
+	// point to the same block.
+	hashes := make([]map[vote]string, len(heads))
+	counts := map[vote]map[string]int{}
+	for i, targetHeads := range heads {
+		hashes[i] = map[vote]string{}
+		for _, head := range targetHeads {
+			if head.Hash == "" {
+				continue
+			}
+			key := vote{chain: chainOf(chains, i), number: head.Number}
+			if _, ok := hashes[i][key]; ok {
+				continue
+			}
+			hashes[i][key] = head.Hash
+			if counts[key] == nil {
+				counts[key] = map[string]int{}
+			}
+			counts[key][head.Hash]++
+		}
+	}
+
+	majority := make(map[vote]string, len(counts))
+	for key, byHash := range counts {
+		voters := 0
+		for _, count := range byHash {
+			voters += count
+		}
+		for hash, count := range byHash {
+			if count*2 > voters {
+				majority[key] = hash
+			}
+		}
+	}
+
+	diverged := map[int]bool{}
+	for i := range hashes {
+		for key, hash := range hashes[i] {
+			if expected, ok := majority[key]; ok && expected != hash {
+				diverged[i] = true
+			}
+		}
+	}
+
+	return diverged
+}
+
+func chainOf(chains []string, index int) string {
+	if index < len(chains) {
+		return chains[index]
+	}
+
+	return ""
+}
+
+// checkForks marks the targets on a minority fork as diverged, and the
+// other ones as not.
+func (h *HealthcheckManager) checkForks() {
+	heads := make([]map[string]BlockHead, 0, len(h.healthcheckers))
+	for _, healthChecker := range h.healthcheckers {
+		heads = append(heads, healthChecker.Heads())
+	}
+
+	diverged := divergedTargets(h.chains, heads)
+	for i, healthChecker := range h.healthcheckers {
+		healthChecker.SetDiverged(diverged[i])
+	}
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDivergedTargets(t *testing.T) {
+	canonical := map[string]BlockHead{
+		BlockTagLatest:    {Number: 100, Hash: "0xaa"},
+		BlockTagFinalized: {Number: 64, Hash: "0x64"},
+	}
+	fork := map[string]BlockHead{
+		BlockTagLatest:    {Number: 100, Hash: "0xbb"},
+		BlockTagFinalized: {Number: 64, Hash: "0x64"},
+	}
+	ahead := map[string]BlockHead{
+		BlockTagLatest:    {Number: 101, Hash: "0xcc"},
+		BlockTagFinalized: {Number: 64, Hash: "0x64"},
+	}
+
+	// The target disagreeing with the majority at a height is diverged.
+	assert.Equal(t, map[int]bool{2: true}, divergedTargets(nil, []map[string]BlockHead{canonical, canonical, fork, ahead}))
+	// Without a strict majority nothing is decided.
+	assert.Empty(t, divergedTargets(nil, []map[string]BlockHead{canonical, fork, ahead}))
+	// Nor are the heights of different chains compared.
+	assert.Empty(t, divergedTargets([]string{ChainEVM, ChainEVM, "other"}, []map[string]BlockHead{canonical, canonical, fork}))
+	// Targets without heads do not vote.
+	assert.Empty(t, divergedTargets(nil, []map[string]BlockHead{canonical, nil, {}}))
+}
+
+func TestForkTracking(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	fakeRPCServer := func(latestHash string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var request RPCRequest
+			json.NewDecoder(r.Body).Decode(&request)
+
+			var params []interface{}
+			json.Unmarshal(request.Params, &params)
+			block := `{"number":"0x64","hash":"` + latestHash + `"}`
+			switch params[0] {
+			case BlockTagSafe:
+				block = `{"number":"0x60","hash":"0x60"}`
+			case BlockTagFinalized:
+				block = `{"number":"0x40","hash":"0x40"}`
+			}
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, request.ID, block)
+		}))
+	}
+
+	var targets []TargetConfig
+	for i, hash := range []string{"0xaa", "0xaa", "0xbb"} {
+		server := fakeRPCServer(hash)
+		defer server.Close()
+		targets = append(targets, TargetConfig{
+			Name: fmt.Sprintf("Node%d", i),
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: server.URL,
+				},
+			},
+		})
+	}
+
+	manager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: targets,
+		Config: HealthCheckConfig{
+			Interval: time.Second,
+			Timeout:  time.Second,
+		},
+	})
+	for _, healthChecker := range manager.healthcheckers {
+		healthChecker.(*RPCHealthchecker).checkAndSetHeads()
+	}
+	markHealthy(manager)
+	assert.Equal(t, uint64(0x40), manager.healthcheckers[0].FinalizedBlockNumber())
+	assert.Equal(t, BlockHead{Number: 0x64, Hash: "0xbb"}, manager.healthcheckers[2].Heads()[BlockTagLatest])
+
+	manager.checkForks()
+	assert.False(t, manager.healthcheckers[0].IsDiverged())
+	assert.False(t, manager.healthcheckers[1].IsDiverged())
+	assert.True(t, manager.healthcheckers[2].IsDiverged())
+	assert.False(t, manager.IsTargetHealthy("Node2"))
+	assert.True(t, manager.IsTargetHealthy("Node0"))
+}
@@ -0,0 +1,431 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	methodGetLogs = "eth_getLogs"
+
+	defaultGetLogsConcurrency = 4
+	defaultGetLogsMaxChunks   = 100
+	// How many times a failing chunk is halved before giving up.
+	maxLogsBisectDepth = 2
+)
+
+// logsFilter is the filter object of eth_getLogs. Fields the gateway doesn't
+// care about (address, topics) are kept as is.
+type logsFilter map[string]json.RawMessage
+
+// logsRange resolves the block range of the filter. Block tags resolve to
+// the cluster head.
+func (h *Proxy) logsRange(request *RPCRequest) (logsFilter, uint64, uint64, bool) {
+	if request.Method != methodGetLogs {
+		return nil, 0, 0, false
+	}
+
+	param, ok := request.Param(0)
+	if !ok {
+		return nil, 0, 0, false
+	}
+
+	filter := logsFilter{}
+	if err := json.Unmarshal(param, &filter); err != nil {
+		return nil, 0, 0, false
+	}
+	if _, ok := filter["blockHash"]; ok {
+		return nil, 0, 0, false
+	}
+
+	from, ok := h.resolveLogsBlock(filter["fromBlock"])
+	if !ok {
+		return nil, 0, 0, false
+	}
+	to, ok := h.resolveLogsBlock(filter["toBlock"])
+	if !ok || to < from {
+		return nil, 0, 0, false
+	}
+
+	return filter, from, to, true
+}
+
+func (h *Proxy) resolveLogsBlock(raw json.RawMessage) (uint64, bool) {
+	block := BlockReference{Tag: "latest"}
+	if len(raw) > 0 {
+		var ok bool
+		if block, ok = parseBlockReference(raw); !ok {
+			return 0, false
+		}
+	}
+
+	switch {
+	case block.HasNumber():
+		return block.Number, true
+	case block.Tag == "earliest":
+		return 0, true
+	case block.Hash != "":
+		return 0, false
+	}
+
+	head := h.clusterHead(defaultTargetGroup)()
+
+	return head, head > 0
+}
+
+// logsTargets returns the healthy targets eth_getLogs can be sent to.
+func (h *Proxy) logsTargets() []*HTTPTarget {
+	var targets []*HTTPTarget
+	for _, target := range h.GetHealthyTargets() {
+		if target.Config.Group == defaultTargetGroup && target.Config.Firewall.Allows(methodGetLogs) {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets
+}
+
+func supportsLogsRange(target *HTTPTarget, blocks uint64) bool {
+	maxBlockRange := target.Config.GetLogs.MaxBlockRange
+	return maxBlockRange == 0 || maxBlockRange >= blocks
+}
+
+// logsRangeFilter prefers the targets that accept the whole block range of
+// an eth_getLogs request.
+func (h *Proxy) logsRangeFilter(r *http.Request) TargetFilter {
+	request, ok := GetRequestBodyFromContext(r).SingleRequest()
+	if !ok {
+		return nil
+	}
+
+	_, from, to, ok := h.logsRange(request)
+	if !ok {
+		return nil
+	}
+
+	return func(index int, _ Healthchecker) bool {
+		return supportsLogsRange(h.targets[index], to-from+1)
+	}
+}
+
+// serveSplitLogs splits an eth_getLogs request no healthy target accepts as
+// a whole into chunks, runs them in parallel across the targets and merges
+// the logs into a single response. It returns false when the request doesn't
+// need splitting.
+func (h *Proxy) serveSplitLogs(w http.ResponseWriter, r *http.Request, request *RPCRequest) bool {
+	if !h.config.Proxy.GetLogs.Split {
+		return false
+	}
+
+	filter, from, to, ok := h.logsRange(request)
+	if !ok {
+		return false
+	}
+
+	targets := h.logsTargets()
+	chunkSize := uint64(0)
+	for _, target := range targets {
+		if supportsLogsRange(target, to-from+1) {
+			return false
+		}
+		if target.Config.GetLogs.MaxBlockRange > chunkSize {
+			chunkSize = target.Config.GetLogs.MaxBlockRange
+		}
+	}
+	if chunkSize == 0 {
+		return false
+	}
+
+	// Every chunk is an upstream call, a client must not be able to make
+	// thousands of them with a single request.
+	maxChunks := h.config.Proxy.GetLogs.MaxChunks
+	if maxChunks == 0 {
+		maxChunks = defaultGetLogsMaxChunks
+	}
+	if chunks := (to-from)/chunkSize + 1; chunks > maxChunks {
+		message := fmt.Sprintf("block range too wide: %d blocks, at most %d are served", to-from+1, maxChunks*chunkSize)
+		writeRPCResponse(w, NewRPCError(request.ID, rpcErrorInvalidParams, message))
+		return true
+	}
+
+	start := time.Now()
+	splitter := &logsSplitter{
+		request:     request,
+		filter:      filter,
+		targets:     targets,
+		exceptions:  h.exceptionsOf,
+		concurrency: h.config.Proxy.GetLogs.Concurrency,
+	}
+	logs, err := splitter.fetch(r.Context(), from, to, chunkSize)
+	if err != nil {
+		zap.L().Warn("failed to fetch split logs", zap.Uint64("fromBlock", from), zap.Uint64("toBlock", to), zap.Error(err))
+		writeRPCResponse(w, NewRPCError(request.ID, rpcErrorServer, err.Error()))
+		return true
+	}
+
+	response, err := NewRPCResult(request.ID, logs)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return true
+	}
+
+	w.Header().Set(providerHeader, strings.Join(splitter.providers(), ","))
+	writeRPCResponse(w, response)
+	h.observeResponseTime(r, "split", time.Since(start))
+
+	return true
+}
+
+type logsSplitter struct {
+	request     *RPCRequest
+	filter      logsFilter
+	targets     []*HTTPTarget
+	exceptions  func(target TargetConfig) []Exception
+	concurrency uint
+
+	mu   sync.Mutex
+	next int
+	used map[string]bool
+}
+
+type logsChunk struct {
+	from, to uint64
+	logs     []json.RawMessage
+	err      error
+}
+
+// fetch runs the chunks of the range in parallel and merges the logs.
+func (s *logsSplitter) fetch(ctx context.Context, from, to, chunkSize uint64) ([]json.RawMessage, error) {
+	var chunks []*logsChunk
+	for chunkFrom := from; chunkFrom <= to; chunkFrom += chunkSize {
+		chunkTo := chunkFrom + chunkSize - 1
+		if chunkTo > to || chunkTo < chunkFrom {
+			chunkTo = to
+		}
+		chunks = append(chunks, &logsChunk{from: chunkFrom, to: chunkTo})
+		if chunkTo == to {
+			break
+		}
+	}
+
+	concurrency := s.concurrency
+	if concurrency == 0 {
+		concurrency = defaultGetLogsConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, chunk := range chunks {
+		// A goroutine per running chunk only, the next one starts when a
+		// slot is released.
+		semaphore <- struct{}{}
+		if ctx.Err() != nil {
+			<-semaphore
+			chunk.err = ctx.Err()
+			break
+		}
+
+		wg.Add(1)
+		go func(chunk *logsChunk) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			chunk.logs, chunk.err = s.fetchRange(ctx, chunk.from, chunk.to, 0)
+			if chunk.err != nil {
+				cancel()
+			}
+		}(chunk)
+	}
+	wg.Wait()
+
+	var logs []json.RawMessage
+	for _, chunk := range chunks {
+		if chunk.err != nil {
+			return nil, chunk.err
+		}
+		logs = append(logs, chunk.logs...)
+	}
+	sortLogs(logs)
+
+	if logs == nil {
+		logs = []json.RawMessage{}
+	}
+
+	return logs, nil
+}
+
+// fetchRange fetches the logs of a single chunk, trying the next target on
+// failure. A chunk hitting the result limit of a target, or refused by every
+// target, is bisected.
+func (s *logsSplitter) fetchRange(ctx context.Context, from, to uint64, depth int) ([]json.RawMessage, error) {
+	var lastErr error
+	for _, target := range s.targetsFor(to - from + 1) {
+		logs, err := s.call(ctx, target, from, to)
+		if err == nil {
+			maxResults := target.Config.GetLogs.MaxResults
+			if maxResults > 0 && uint64(len(logs)) >= maxResults && from < to {
+				// The logs may be truncated.
+				return s.bisect(ctx, from, to, depth)
+			}
+			return logs, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		lastErr = errors.Wrapf(err, "%s: blocks %d-%d", target.Config.Name, from, to)
+	}
+
+	if lastErr == nil {
+		return nil, fmt.Errorf("no target supports a range of %d blocks", to-from+1)
+	}
+
+	// A smaller range is more likely to succeed, e.g. when the response
+	// would be too large, but don't multiply calls to failing targets.
+	if from < to && depth < maxLogsBisectDepth {
+		return s.bisect(ctx, from, to, depth+1)
+	}
+
+	return nil, lastErr
+}
+
+func (s *logsSplitter) bisect(ctx context.Context, from, to uint64, depth int) ([]json.RawMessage, error) {
+	middle := from + (to-from)/2
+
+	left, err := s.fetchRange(ctx, from, middle, depth)
+	if err != nil {
+		return nil, err
+	}
+	right, err := s.fetchRange(ctx, middle+1, to, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(left, right...), nil
+}
+
+// targetsFor returns the targets accepting the range size, rotated so the
+// chunks are spread across the targets.
+func (s *logsSplitter) targetsFor(blocks uint64) []*HTTPTarget {
+	var eligible []*HTTPTarget
+	for _, target := range s.targets {
+		if supportsLogsRange(target, blocks) {
+			eligible = append(eligible, target)
+		}
+	}
+	if len(eligible) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	offset := s.next % len(eligible)
+	s.next++
+	s.mu.Unlock()
+
+	return append(eligible[offset:], eligible[:offset]...)
+}
+
+func (s *logsSplitter) call(ctx context.Context, target *HTTPTarget, from, to uint64) ([]json.RawMessage, error) {
+	filter := logsFilter{}
+	for key, value := range s.filter {
+		filter[key] = value
+	}
+	filter["fromBlock"], _ = json.Marshal(hexutil.EncodeUint64(from))
+	filter["toBlock"], _ = json.Marshal(hexutil.EncodeUint64(to))
+
+	request, err := s.request.WithParam(0, filter)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot encode request")
+	}
+
+	data, err := target.Call(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	if message, ok := matchException(string(data), s.exceptions(target.Config)); ok {
+		return nil, errors.New(message)
+	}
+
+	response, err := ParseRPCResponse(data)
+	if err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, errors.New(response.Error.Message)
+	}
+
+	var logs []json.RawMessage
+	if err := json.Unmarshal(response.Result, &logs); err != nil {
+		return nil, errors.Wrap(err, "cannot decode logs")
+	}
+
+	s.mu.Lock()
+	if s.used == nil {
+		s.used = map[string]bool{}
+	}
+	s.used[target.Config.Name] = true
+	s.mu.Unlock()
+
+	return logs, nil
+}
+
+// providers returns the names of the targets that served a chunk.
+func (s *logsSplitter) providers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	providers := make([]string, 0, len(s.used))
+	for name := range s.used {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+
+	return providers
+}
+
+// sortLogs orders logs by block number and log index.
+func sortLogs(logs []json.RawMessage) {
+	type position struct {
+		BlockNumber hexutil.Uint64 `json:"blockNumber"`
+		LogIndex    hexutil.Uint64 `json:"logIndex"`
+	}
+
+	positions := make([]position, len(logs))
+	for i, log := range logs {
+		_ = json.Unmarshal(log, &positions[i])
+	}
+
+	indexes := make([]int, len(logs))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		pa, pb := positions[indexes[a]], positions[indexes[b]]
+		if pa.BlockNumber != pb.BlockNumber {
+			return pa.BlockNumber < pb.BlockNumber
+		}
+		return pa.LogIndex < pb.LogIndex
+	})
+
+	sorted := make([]json.RawMessage, len(logs))
+	for i, index := range indexes {
+		sorted[i] = logs[index]
+	}
+	copy(logs, sorted)
+}
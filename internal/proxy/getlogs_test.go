@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+// logsServer answers eth_getLogs with a log per block of the range and
+// refuses ranges wider than maxBlockRange.
+func logsServer(t *testing.T, maxBlockRange uint64) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Params []struct {
+				FromBlock hexutil.Uint64 `json:"fromBlock"`
+				ToBlock   hexutil.Uint64 `json:"toBlock"`
+			} `json:"params"`
+		}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&request))
+		from, to := uint64(request.Params[0].FromBlock), uint64(request.Params[0].ToBlock)
+
+		if to-from+1 > maxBlockRange {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"block range is too wide"}}`))
+			return
+		}
+
+		var logs []string
+		for block := from; block <= to; block++ {
+			logs = append(logs, fmt.Sprintf(`{"blockNumber":"%s","logIndex":"0x0"}`, hexutil.EncodeUint64(block)))
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":[` + strings.Join(logs, ",") + `]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGetLogsSplitting(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Proxy.GetLogs = GetLogsConfig{Split: true}
+	rpcGatewayConfig.Exceptions = []Exception{
+		{Match: "block range is too wide"},
+	}
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "Server1",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: logsServer(t, 10).URL,
+				},
+			},
+			GetLogs: GetLogsLimits{MaxBlockRange: 10},
+		},
+		{
+			Name: "Server2",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: logsServer(t, 10).URL,
+				},
+			},
+			GetLogs: GetLogsLimits{MaxBlockRange: 10, MaxResults: 4},
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	markHealthy(healthcheckManager)
+
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	requestBody := bytes.NewBufferString(`{"jsonrpc":"2.0","id":3,"method":"eth_getLogs","params":[{"fromBlock":"0x0","toBlock":"0x18","address":"0x0000000000000000000000000000000000000000"}]}`)
+	req, err := http.NewRequest("POST", "/", requestBody)
+	assert.Nil(t, err)
+
+	rr := httptest.NewRecorder()
+	httpFailoverProxy.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	response, err := ParseRPCResponse(rr.Body.Bytes())
+	assert.Nil(t, err)
+	assert.Nil(t, response.Error)
+	assert.Equal(t, json.RawMessage("3"), response.ID)
+
+	var logs []struct {
+		BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	}
+	assert.Nil(t, json.Unmarshal(response.Result, &logs))
+	assert.Len(t, logs, 25)
+	for i, log := range logs {
+		assert.Equal(t, uint64(i), uint64(log.BlockNumber))
+	}
+}
+
+func TestGetLogsSplittingLimits(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	var calls atomic.Int32
+	busyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`SERVER_BUSY`))
+	}))
+	defer busyServer.Close()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Chain = ChainConfig{Default: ChainTron}
+	rpcGatewayConfig.Proxy.GetLogs = GetLogsConfig{Split: true, MaxChunks: 2}
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "Server1",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: busyServer.URL,
+				},
+			},
+			GetLogs: GetLogsLimits{MaxBlockRange: 10},
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+		Chain:   rpcGatewayConfig.Chain,
+	})
+	markHealthy(healthcheckManager)
+
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	serve := func(toBlock string) *RPCResponse {
+		requestBody := bytes.NewBufferString(`{"jsonrpc":"2.0","id":3,"method":"eth_getLogs","params":[{"fromBlock":"0x0","toBlock":"` + toBlock + `"}]}`)
+		req, err := http.NewRequest("POST", "/", requestBody)
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		response, err := ParseRPCResponse(rr.Body.Bytes())
+		assert.Nil(t, err)
+		return response
+	}
+
+	// A range needing more chunks than allowed is rejected upfront.
+	response := serve("0x18")
+	assert.Equal(t, rpcErrorInvalidParams, response.Error.Code)
+	assert.Equal(t, "block range too wide: 25 blocks, at most 20 are served", response.Error.Message)
+	assert.Equal(t, int32(0), calls.Load())
+
+	// The errors of the chain family are classified like on a single call.
+	response = serve("0x13")
+	assert.Equal(t, rpcErrorServer, response.Error.Code)
+	assert.Contains(t, response.Error.Message, "Tron: server busy")
+	assert.NotZero(t, calls.Load())
+}
@@ -4,11 +4,12 @@ import (
 	"context"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/0xProject/rpc-gateway/internal/metrics"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/rpc"
-	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 )
 
@@ -16,6 +17,9 @@ const (
 	MetricBlockNumber int = iota
 	MetricGasLimit
 	MetricResponseTime
+	MetricSLO
+	MetricProbes
+	MetricFinalizedBlockNumber
 )
 
 const (
@@ -26,10 +30,18 @@ type Healthchecker interface {
 	Start(ctx context.Context)
 	Stop(ctx context.Context) error
 	IsHealthy() bool
+	Checked() bool
 	BlockNumber() uint64
+	FinalizedBlockNumber() uint64
+	Heads() map[string]BlockHead
+	IsDiverged() bool
+	SetDiverged(diverged bool)
 	Taint()
 	RemoveTaint()
 	IsTainted() bool
+	IsArchive() bool
+	Capabilities() map[string]bool
+	SupportsMethod(method string) bool
 	Name() string
 	SetMetric(int, interface{})
 }
@@ -37,7 +49,23 @@ type Healthchecker interface {
 type RPCHealthcheckerConfig struct {
 	URL  string
 	Name string // identifier imported from RPC gateway config
-	Solana bool // if Solana
+	// Chain family of the node, "evm" when empty.
+	Chain string
+	// Whether the node serves historical state, probed when nil.
+	Archive *bool
+	// Methods probed to find out which ones the node supports.
+	Capabilities CapabilitiesConfig
+	// Calls made on every check, on top of the built-in ones.
+	Probes []ProbeConfig
+	// Limits of the Solana health profile.
+	SolanaHealth SolanaHealthConfig
+	// Limits of the Tron health profile.
+	TronHealth TronHealthConfig
+	// Limits of the Sui health profile.
+	SuiHealth SuiHealthConfig
+	// Returns the highest block (slot, checkpoint) of the healthy nodes of
+	// the same chain.
+	ClusterHead func() uint64
 
 	// How often to check health.
 	Interval time.Duration `yaml:"healthcheckInterval"`
@@ -65,12 +93,21 @@ type RPCHealthchecker struct {
 	client     *rpc.Client
 	httpClient *http.Client
 	config     RPCHealthcheckerConfig
+	chain      chainFamily
 
 	// latest known blockNumber from the RPC.
 	blockNumber uint64
 	// gasLimit received from the GasLeft.sol contract call.
 	gasLimit uint64
 
+	// whether the node serves historical state, valid once archiveProbed.
+	isArchive     bool
+	archiveProbed bool
+
+	// probed methods and whether the node supports them.
+	capabilities         map[string]bool
+	lastCapabilityProbes time.Time
+
 	// RPCHealthChecker can be tainted by the abstraction on top. Reasons:
 	// Forced failover
 	// Blocknumber is behind the other
@@ -80,20 +117,52 @@ type RPCHealthchecker struct {
 	// The current wait time for the taint removal
 	currentTaintWaitTime time.Duration
 
-	// is the ethereum RPC node healthy according to the RPCHealthchecker
+	// is the ethereum RPC node healthy according to the RPCHealthchecker,
+	// unknown and false until the first round of checks completes.
 	isHealthy bool
+	// whether a round of liveness checks and probes completed.
+	checked bool
+
+	// the consecutive failures (weighted) and successful rounds of the
+	// liveness checks and probes, counted towards the thresholds.
+	probeFailures  uint
+	probeSuccesses uint
+	// whether a round of liveness checks and probes is running.
+	probing atomic.Bool
+
+	// the latest, safe and finalized blocks of the node, by block tag.
+	heads map[string]BlockHead
+	// whether the node is on a minority fork, set by the manager comparing
+	// the heads of the nodes.
+	isDiverged bool
+
+	// the latest blockhash of a Solana node and when it last changed.
+	blockhash          string
+	blockhashChangedAt time.Time
 
 	// health check ticker
 	ticker *time.Ticker
-	mu     sync.RWMutex
+	// closed by Stop, ends the check loop and the pending taint removals.
+	done     chan struct{}
+	stopOnce sync.Once
+	mu       sync.RWMutex
 
 	// metrics
-	metricResponseTime           *prometheus.HistogramVec
-	metricRPCProviderBlockNumber *prometheus.GaugeVec
-	metricRPCProviderGasLimit    *prometheus.GaugeVec
+	metricResponseTime           *metrics.HistogramVec
+	metricRPCProviderBlockNumber *metrics.GaugeVec
+	metricRPCProviderGasLimit    *metrics.GaugeVec
+	metricProbes                 *metrics.CounterVec
+	metricFinalizedBlockNumber   *metrics.GaugeVec
+	// rolling latency and availability of the checks.
+	slo *sloWindow
 }
 
 func NewHealthchecker(config RPCHealthcheckerConfig) (Healthchecker, error) {
+	chain, err := chainFamilyByName(config.Chain)
+	if err != nil {
+		return nil, err
+	}
+
 	client, err := rpc.Dial(config.URL)
 	if err != nil {
 		return nil, err
@@ -105,8 +174,9 @@ func NewHealthchecker(config RPCHealthcheckerConfig) (Healthchecker, error) {
 		client:               client,
 		httpClient:           &http.Client{},
 		config:               config,
-		isHealthy:            true,
+		chain:                chain,
 		currentTaintWaitTime: initialTaintWaitTime,
+		done:                 make(chan struct{}),
 	}
 
 	return healthchecker, nil
@@ -119,11 +189,17 @@ func (h *RPCHealthchecker) Name() string {
 func (h *RPCHealthchecker) SetMetric(i int, metric interface{}) {
 	switch i {
 	case MetricBlockNumber:
-		h.metricRPCProviderBlockNumber = metric.(*prometheus.GaugeVec)
+		h.metricRPCProviderBlockNumber = metric.(*metrics.GaugeVec)
 	case MetricGasLimit:
-		h.metricRPCProviderGasLimit = metric.(*prometheus.GaugeVec)
+		h.metricRPCProviderGasLimit = metric.(*metrics.GaugeVec)
 	case MetricResponseTime:
-		h.metricResponseTime = metric.(*prometheus.HistogramVec)
+		h.metricResponseTime = metric.(*metrics.HistogramVec)
+	case MetricSLO:
+		h.slo = metric.(*sloWindow)
+	case MetricProbes:
+		h.metricProbes = metric.(*metrics.CounterVec)
+	case MetricFinalizedBlockNumber:
+		h.metricFinalizedBlockNumber = metric.(*metrics.GaugeVec)
 	default:
 		zap.L().Warn("invalid metric type, ignoring.")
 	}
@@ -142,10 +218,10 @@ func (h *RPCHealthchecker) checkBlockNumber(ctx context.Context) (uint64, error)
 	}
 	duration := time.Since(start)
 	if h.metricResponseTime != nil {
-		h.metricResponseTime.WithLabelValues(h.config.Name, "eth_blockNumber").Observe(duration.Seconds())
+		h.metricResponseTime.Observe(duration.Seconds(), h.config.Name, "eth_blockNumber")
 	}
 	if h.metricRPCProviderBlockNumber != nil {
-		h.metricRPCProviderBlockNumber.WithLabelValues(h.config.Name).Set(float64(blockNumber))
+		h.metricRPCProviderBlockNumber.Set(float64(blockNumber), h.config.Name)
 	}
 	zap.L().Debug("fetched block", zap.Uint64("blockNumber", uint64(blockNumber)), zap.String("rpcProvider", h.config.Name))
 
@@ -165,10 +241,10 @@ func (h *RPCHealthchecker) checkSolanaSlotNumber(ctx context.Context) (uint64, e
 	}
 	duration := time.Since(start)
 	if h.metricResponseTime != nil {
-		h.metricResponseTime.WithLabelValues(h.config.Name, "eth_blockNumber").Observe(duration.Seconds())
+		h.metricResponseTime.Observe(duration.Seconds(), h.config.Name, "getSlot")
 	}
 	if h.metricRPCProviderBlockNumber != nil {
-		h.metricRPCProviderBlockNumber.WithLabelValues(h.config.Name).Set(float64(blockNumber))
+		h.metricRPCProviderBlockNumber.Set(float64(blockNumber), h.config.Name)
 	}
 	zap.L().Debug("fetched slot", zap.Uint64("slotNumber", uint64(blockNumber)), zap.String("rpcProvider", h.config.Name))
 
@@ -182,7 +258,7 @@ func (h *RPCHealthchecker) checkSolanaSlotNumber(ctx context.Context) (uint64, e
 func (h *RPCHealthchecker) checkGasLimit(ctx context.Context) (uint64, error) {
 	gasLimit, err := performGasLeftCall(ctx, h.httpClient, h.config.URL)
 	if h.metricRPCProviderGasLimit != nil {
-		h.metricRPCProviderGasLimit.WithLabelValues(h.config.Name).Set(float64(gasLimit))
+		h.metricRPCProviderGasLimit.Set(float64(gasLimit), h.config.Name)
 	}
 	zap.L().Debug("fetched gas limit", zap.Uint64("gasLimit", gasLimit), zap.String("rpcProvider", h.config.Name))
 	if err != nil {
@@ -193,13 +269,73 @@ func (h *RPCHealthchecker) checkGasLimit(ctx context.Context) (uint64, error) {
 	return gasLimit, nil
 }
 
+// checkAndSetGasLimit is the liveness check of EVM nodes.
+func (h *RPCHealthchecker) checkAndSetGasLimit() error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	gasLimit, err := h.checkGasLimit(ctx)
+	h.slo.observeCheck(time.Since(start), err)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.gasLimit = gasLimit
+
+	return nil
+}
+
 // CheckAndSetHealth makes the following calls
-// - `eth_blockNumber` - to get the latest block reported by the node
-// - `eth_call` - to get the gas limit
+// - `eth_blockNumber` (`getSlot` on Solana) - to get the latest block reported by the node
+// - `eth_getBlockByNumber` - to record the latest, safe and finalized blocks
+// - `eth_getBalance` - once, to find out whether the node is an archive node
+// - the capability probes, to find out which methods the node supports
+// - the liveness checks of the chain family, e.g. an `eth_call` to get the gas limit
+// - the probes of the config
 // And sets the health status based on the responses.
 func (h *RPCHealthchecker) CheckAndSetHealth() {
 	go h.checkAndSetBlockNumberHealth()
-	go h.checkAndSetGasLeftHealth()
+	go h.checkAndSetHeads()
+	go h.checkAndSetArchive()
+	go h.checkAndSetCapabilities()
+	go h.checkAndSetProbesHealth()
+}
+
+// checkAndSetArchive finds out once whether the node serves historical
+// state, on the chains where nodes may not.
+func (h *RPCHealthchecker) checkAndSetArchive() {
+	prober, ok := h.chain.(archiveProber)
+	if h.config.Archive != nil || !ok {
+		return
+	}
+
+	h.mu.RLock()
+	probed := h.archiveProbed
+	h.mu.RUnlock()
+	if probed {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	isArchive, err := prober.ProbeArchive(ctx, h)
+
+	// Only a missing state tells the node is not an archive node, the probe
+	// is repeated on any other error.
+	if err != nil {
+		zap.L().Warn("failed probing archive state", zap.Error(err), zap.String("rpcProvider", h.config.Name))
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.isArchive = isArchive
+	h.archiveProbed = true
+	zap.L().Info("probed archive state", zap.Bool("archive", h.isArchive), zap.String("rpcProvider", h.config.Name))
 }
 
 func (h *RPCHealthchecker) checkAndSetBlockNumberHealth() {
@@ -213,11 +349,9 @@ func (h *RPCHealthchecker) checkAndSetBlockNumberHealth() {
 	// health checking but it provides additional context.
 	var blockNumber uint64
     var err error
-	if h.config.Solana {
-		blockNumber, err = h.checkSolanaSlotNumber(ctx)
-	} else {
-		blockNumber, err = h.checkBlockNumber(ctx)
-	}
+	start := time.Now()
+	blockNumber, err = h.chain.HeadHeight(ctx, h)
+	h.slo.observeCheck(time.Since(start), err)
 	if err != nil {
 		return
 	}
@@ -227,25 +361,6 @@ func (h *RPCHealthchecker) checkAndSetBlockNumberHealth() {
 	h.blockNumber = blockNumber
 }
 
-func (h *RPCHealthchecker) checkAndSetGasLeftHealth() {
-	if h.config.Solana {
-		return
-	}
-	ctx := context.Background()
-	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
-	defer cancel()
-
-	gasLimit, err := h.checkGasLimit(ctx)
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	if err != nil {
-		h.isHealthy = false
-		return
-	}
-	h.gasLimit = gasLimit
-	h.isHealthy = true
-}
-
 func (h *RPCHealthchecker) Start(ctx context.Context) {
 	h.CheckAndSetHealth()
 	ticker := time.NewTicker(h.config.Interval)
@@ -255,14 +370,29 @@ func (h *RPCHealthchecker) Start(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
+		case <-h.done:
+			return
 		case <-ticker.C:
 			h.CheckAndSetHealth()
 		}
 	}
 }
 
+// Stop ends the check loop and closes the connections to the node. Checks
+// already in flight fail.
 func (h *RPCHealthchecker) Stop(_ context.Context) error {
-	// TODO: Additional cleanups?
+	h.stopOnce.Do(func() {
+		if h.done != nil {
+			close(h.done)
+		}
+		if h.client != nil {
+			h.client.Close()
+		}
+		if h.httpClient != nil {
+			h.httpClient.CloseIdleConnections()
+		}
+	})
+
 	return nil
 }
 
@@ -275,9 +405,23 @@ func (h *RPCHealthchecker) IsHealthy() bool {
 		return false
 	}
 
+	if h.isDiverged {
+		// Neither is a node on a minority fork
+		return false
+	}
+
 	return h.isHealthy
 }
 
+// Checked reports whether the first round of liveness checks and probes
+// completed. Until then the health of the node is unknown.
+func (h *RPCHealthchecker) Checked() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.checked
+}
+
 func (h *RPCHealthchecker) BlockNumber() uint64 {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -285,6 +429,17 @@ func (h *RPCHealthchecker) BlockNumber() uint64 {
 	return h.blockNumber
 }
 
+func (h *RPCHealthchecker) IsArchive() bool {
+	if h.config.Archive != nil {
+		return *h.config.Archive
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.isArchive
+}
+
 func (h *RPCHealthchecker) IsTainted() bool {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -309,10 +464,15 @@ func (h *RPCHealthchecker) Taint() {
 		h.currentTaintWaitTime = initialTaintWaitTime
 	}
 	zap.L().Info("RPC Tainted", zap.String("name", h.config.Name), zap.Int64("taintWaitTime", int64(h.currentTaintWaitTime)))
-	go func() {
-		<-time.After(h.currentTaintWaitTime)
-		h.RemoveTaint()
-	}()
+	go func(wait time.Duration) {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			h.RemoveTaint()
+		case <-h.done:
+		}
+	}(h.currentTaintWaitTime)
 }
 
 func (h *RPCHealthchecker) RemoveTaint() {
@@ -63,7 +63,7 @@ func TestSolanaHealthchecker(t *testing.T) {
 		Timeout:          2 * time.Second,
 		FailureThreshold: 1,
 		SuccessThreshold: 1,
-		Solana:           true,
+		Chain:            ChainSolana,
 		}
 
 		healthchecker, err := NewHealthchecker(healtcheckConfig)
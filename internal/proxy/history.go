@@ -0,0 +1,150 @@
+package proxy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	requestIDHeader       = "X-Request-Id"
+	attemptsHeader        = "X-Rpc-Attempts"
+	failedProvidersHeader = "X-Rpc-Failed-Providers"
+	providerHeader        = "X-Rpc-Provider"
+	maxRequestIDLength    = 128
+)
+
+// RequestHistory records the upstream attempts made for a client request.
+// Attempts are made one after another, it's not safe for concurrent use.
+type RequestHistory struct {
+	ID       string
+	Start    time.Time
+	Attempts []*Attempt
+}
+
+// Attempt is a single upstream round trip.
+type Attempt struct {
+	Target     string  `json:"target"`
+	Status     int     `json:"status,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+
+	start   time.Time
+	elapsed time.Duration
+	done    bool
+}
+
+func newRequestHistory(r *http.Request) *RequestHistory {
+	return &RequestHistory{
+		ID:    requestID(r),
+		Start: time.Now(),
+	}
+}
+
+// requestID keeps the id sent by the client, or makes up one.
+func requestID(r *http.Request) string {
+	if id := r.Header.Get(requestIDHeader); id != "" && len(id) <= maxRequestIDLength {
+		return id
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(id)
+}
+
+// forwardRequestID passes the request id on to the upstream.
+func forwardRequestID(ctx context.Context, header http.Header) {
+	if history, ok := ctx.Value(History).(*RequestHistory); ok && history.ID != "" {
+		header.Set(requestIDHeader, history.ID)
+	}
+}
+
+// setRoutingHeaders describes the attempts made so far to the client.
+func setRoutingHeaders(w http.ResponseWriter, history *RequestHistory) {
+	header := w.Header()
+	header.Set(requestIDHeader, history.ID)
+	if len(history.Attempts) == 0 {
+		return
+	}
+
+	header.Set(attemptsHeader, strconv.Itoa(len(history.Attempts)))
+	if last := history.last(); last.Error == "" {
+		header.Set(providerHeader, last.Target)
+	} else {
+		header.Del(providerHeader)
+	}
+
+	failed := history.Failed()
+	if len(failed) == 0 {
+		header.Del(failedProvidersHeader)
+		return
+	}
+	names := make([]string, 0, len(failed))
+	for _, attempt := range failed {
+		names = append(names, attempt.Target)
+	}
+	header.Set(failedProvidersHeader, strings.Join(names, ","))
+}
+
+// dropRoutingHeaders removes the headers set by the gateway from an upstream
+// response, they would be appended to the ones already set otherwise.
+func dropRoutingHeaders(header http.Header) {
+	for _, name := range []string{requestIDHeader, attemptsHeader, failedProvidersHeader, providerHeader} {
+		header.Del(name)
+	}
+}
+
+func (h *RequestHistory) begin(target string) *Attempt {
+	attempt := &Attempt{Target: target, start: time.Now()}
+	h.Attempts = append(h.Attempts, attempt)
+
+	return attempt
+}
+
+// last returns the attempt in flight.
+func (h *RequestHistory) last() *Attempt {
+	if h == nil || len(h.Attempts) == 0 {
+		return nil
+	}
+
+	return h.Attempts[len(h.Attempts)-1]
+}
+
+// Failed returns the attempts that were retried on a different target.
+func (h *RequestHistory) Failed() []*Attempt {
+	var failed []*Attempt
+	for _, attempt := range h.Attempts {
+		if attempt.Error != "" {
+			failed = append(failed, attempt)
+		}
+	}
+
+	return failed
+}
+
+func (a *Attempt) setStatus(status int) {
+	if a != nil {
+		a.Status = status
+	}
+}
+
+// finish records the outcome of the attempt, only the first call counts.
+func (a *Attempt) finish(err error) {
+	if a == nil || a.done {
+		return
+	}
+
+	a.done = true
+	a.elapsed = time.Since(a.start)
+	a.DurationMs = float64(a.elapsed.Microseconds()) / 1000
+	if err != nil {
+		a.Error = err.Error()
+	}
+}
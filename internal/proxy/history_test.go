@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoutingHeaders(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	// Whichever target is hit first fails.
+	var calls atomic.Int32
+	var requestIDs []string
+	fakeRPCServer := func() *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestIDs = append(requestIDs, r.Header.Get("X-Request-Id"))
+			// Echoed ids must not end up twice in the response.
+			w.Header().Set("X-Request-Id", r.Header.Get("X-Request-Id"))
+			if calls.Add(1) == 1 {
+				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+		}))
+	}
+	fakeRPC1Server := fakeRPCServer()
+	defer fakeRPC1Server.Close()
+	fakeRPC2Server := fakeRPCServer()
+	defer fakeRPC2Server.Close()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "Server1",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPC1Server.URL,
+				},
+			},
+		},
+		{
+			Name: "Server2",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPC2Server.URL,
+				},
+			},
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	serve := func(requestID string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId"}`))
+		assert.Nil(t, err)
+		if requestID != "" {
+			req.Header.Set("X-Request-Id", requestID)
+		}
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		return rr
+	}
+
+	rr := serve("abc")
+	assert.Equal(t, []string{"abc"}, rr.Header().Values("X-Request-Id"))
+	assert.Equal(t, []string{"abc", "abc"}, requestIDs)
+	assert.Equal(t, "2", rr.Header().Get("X-Rpc-Attempts"))
+
+	failed := rr.Header().Get("X-Rpc-Failed-Providers")
+	assert.Contains(t, []string{"Server1", "Server2"}, failed)
+	assert.NotEqual(t, failed, rr.Header().Get("X-Rpc-Provider"))
+
+	// A request id is made up when the client sent none.
+	requestIDs = nil
+	rr = serve("")
+	assert.Len(t, rr.Header().Get("X-Request-Id"), 32)
+	assert.Equal(t, []string{rr.Header().Get("X-Request-Id")}, requestIDs)
+	assert.Equal(t, "1", rr.Header().Get("X-Rpc-Attempts"))
+	assert.Empty(t, rr.Header().Get("X-Rpc-Failed-Providers"))
+}
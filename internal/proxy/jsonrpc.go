@@ -0,0 +1,280 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+const (
+	rpcErrorParse          = -32700
+	rpcErrorMethodNotFound = -32601
+	rpcErrorInvalidParams  = -32602
+	rpcErrorServer         = -32000
+)
+
+// RPCRequest is a single JSON-RPC call as sent by a client.
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// RPCError is the error object of a JSON-RPC response.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// RPCResponse is a single JSON-RPC response as returned by a target.
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// NewRPCResult builds a successful response to request.
+func NewRPCResult(id json.RawMessage, result interface{}) ([]byte, error) {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot encode result")
+	}
+
+	return json.Marshal(RPCResponse{JSONRPC: "2.0", ID: rpcID(id), Result: encoded})
+}
+
+// NewRPCError builds an error response to request.
+func NewRPCError(id json.RawMessage, code int, message string) []byte {
+	response, _ := json.Marshal(RPCResponse{
+		JSONRPC: "2.0",
+		ID:      rpcID(id),
+		Error:   &RPCError{Code: code, Message: message},
+	})
+
+	return response
+}
+
+// rpcID makes sure a response always carries an id, `null` when the
+// request had none.
+func rpcID(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return json.RawMessage("null")
+	}
+
+	return id
+}
+
+// ParseRPCRequests decodes a request body that can be either a single call
+// or a batch of calls. The second return value reports whether it was a
+// batch.
+func ParseRPCRequests(body []byte) ([]RPCRequest, bool, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, false, errors.New("empty body")
+	}
+
+	if body[0] == '[' {
+		var requests []RPCRequest
+		if err := json.Unmarshal(body, &requests); err != nil {
+			return nil, true, errors.Wrap(err, "cannot decode batch request")
+		}
+
+		return requests, true, nil
+	}
+
+	var request RPCRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, false, errors.Wrap(err, "cannot decode request")
+	}
+
+	return []RPCRequest{request}, false, nil
+}
+
+// ParseRPCResponse decodes a single (non batch) JSON-RPC response.
+func ParseRPCResponse(body []byte) (*RPCResponse, error) {
+	response := &RPCResponse{}
+	if err := json.Unmarshal(body, response); err != nil {
+		return nil, errors.Wrap(err, "cannot decode response")
+	}
+
+	return response, nil
+}
+
+// Param returns the positional parameter at index i.
+func (r *RPCRequest) Param(i int) (json.RawMessage, bool) {
+	var params []json.RawMessage
+	if err := json.Unmarshal(r.Params, &params); err != nil {
+		return nil, false
+	}
+	if i < 0 || i >= len(params) {
+		return nil, false
+	}
+
+	return params[i], true
+}
+
+// WithParam returns a copy of the call with the positional parameter at
+// index i replaced. The parameter is appended when i equals the number of
+// parameters.
+func (r *RPCRequest) WithParam(i int, value interface{}) (RPCRequest, error) {
+	request := *r
+
+	var params []json.RawMessage
+	if len(r.Params) > 0 {
+		if err := json.Unmarshal(r.Params, &params); err != nil {
+			return request, errors.Wrap(err, "params are not positional")
+		}
+	}
+	if i < 0 || i > len(params) {
+		return request, errors.New("param index out of range")
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return request, errors.Wrap(err, "cannot encode param")
+	}
+	if i == len(params) {
+		params = append(params, encoded)
+	} else {
+		params[i] = encoded
+	}
+
+	if request.Params, err = json.Marshal(params); err != nil {
+		return request, errors.Wrap(err, "cannot encode params")
+	}
+
+	return request, nil
+}
+
+// EncodeRPCRequests is the reverse of ParseRPCRequests.
+func EncodeRPCRequests(requests []RPCRequest, isBatch bool) ([]byte, error) {
+	if isBatch {
+		return json.Marshal(requests)
+	}
+	if len(requests) != 1 {
+		return nil, errors.New("expected a single request")
+	}
+
+	return json.Marshal(requests[0])
+}
+
+// BlockReference returns the block the call is made against, if the method
+// takes a block parameter and the client provided one.
+func (r *RPCRequest) BlockReference() (BlockReference, bool) {
+	index := blockParamIndex(r.Method)
+	if index < 0 {
+		return BlockReference{}, false
+	}
+
+	raw, ok := r.Param(index)
+	if !ok {
+		// Omitted block parameter defaults to "latest" on most clients.
+		return BlockReference{Tag: "latest"}, true
+	}
+
+	return parseBlockReference(raw)
+}
+
+// IsEmptyResult reports whether the response carries no data, e.g. a node
+// answering `null` for a block or receipt it has not seen yet.
+func (r *RPCResponse) IsEmptyResult() bool {
+	if r.Error != nil {
+		return false
+	}
+
+	switch strings.TrimSpace(string(r.Result)) {
+	case "", "null", `""`, "[]", "{}":
+		return true
+	}
+
+	return false
+}
+
+// BlockReference points to a block either by number, by tag (latest,
+// pending, ...) or by hash.
+type BlockReference struct {
+	Number uint64
+	Tag    string
+	Hash   string
+}
+
+// HasNumber reports whether the reference is an explicit block number.
+func (b BlockReference) HasNumber() bool {
+	return b.Tag == "" && b.Hash == ""
+}
+
+func parseBlockReference(raw json.RawMessage) (BlockReference, bool) {
+	var value string
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return parseBlockReferenceString(value)
+	}
+
+	// EIP-1898 style object.
+	var object struct {
+		BlockNumber string `json:"blockNumber"`
+		BlockHash   string `json:"blockHash"`
+	}
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return BlockReference{}, false
+	}
+	if object.BlockHash != "" {
+		return BlockReference{Hash: object.BlockHash}, true
+	}
+	if object.BlockNumber != "" {
+		return parseBlockReferenceString(object.BlockNumber)
+	}
+
+	return BlockReference{}, false
+}
+
+func parseBlockReferenceString(value string) (BlockReference, bool) {
+	switch value {
+	case "latest", "pending", "earliest", "safe", "finalized":
+		return BlockReference{Tag: value}, true
+	}
+
+	if len(value) == 66 {
+		return BlockReference{Hash: value}, true
+	}
+
+	number, err := hexutil.DecodeUint64(value)
+	if err != nil {
+		return BlockReference{}, false
+	}
+
+	return BlockReference{Number: number}, true
+}
+
+// blockParamIndex returns the position of the block parameter for methods
+// that accept one, or -1 otherwise.
+func blockParamIndex(method string) int {
+	switch method {
+	case "eth_getBlockByNumber",
+		"eth_getBlockTransactionCountByNumber",
+		"eth_getUncleCountByBlockNumber",
+		"eth_getTransactionByBlockNumberAndIndex",
+		"eth_getUncleByBlockNumberAndIndex",
+		"eth_getBlockReceipts",
+		"debug_traceBlockByNumber",
+		"trace_block":
+		return 0
+	case "eth_getBalance",
+		"eth_getCode",
+		"eth_getTransactionCount",
+		"eth_call",
+		"eth_estimateGas",
+		"eth_createAccessList":
+		return 1
+	case "eth_getStorageAt",
+		"eth_getProof":
+		return 2
+	}
+
+	return -1
+}
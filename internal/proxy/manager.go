@@ -4,107 +4,171 @@ import (
 	"context"
 	"math/rand"
 	"strconv"
+	"sync"
 	"time"
 
 	"slices"
 
+	"github.com/0xProject/rpc-gateway/internal/metrics"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.uber.org/zap"
 )
 
 type HealthcheckManagerConfig struct {
 	Targets []TargetConfig
 	Config  HealthCheckConfig
-	Solana  bool
+	// Chain families of the targets.
+	Chain ChainConfig
+	// Registry the metrics are created in, the default registerer when nil.
+	Metrics *metrics.Registry
 }
 
 type HealthcheckManager struct {
 	healthcheckers []Healthchecker
-
-	metricRPCProviderInfo        *prometheus.GaugeVec
-	metricRPCProviderStatus      *prometheus.GaugeVec
-	metricResponseTime           *prometheus.HistogramVec
-	metricRPCProviderBlockNumber *prometheus.GaugeVec
-	metricRPCProviderGasLimit    *prometheus.GaugeVec
+	// rolling windows of the targets, by healthchecker index.
+	slo []*sloWindow
+	// chain families of the targets, by healthchecker index.
+	chains []string
+	// closed by Stop, ends the status loop.
+	done     chan struct{}
+	stopOnce sync.Once
+
+	metricRPCProviderInfo        *metrics.GaugeVec
+	metricRPCProviderStatus      *metrics.GaugeVec
+	metricResponseTime           *metrics.HistogramVec
+	metricRPCProviderBlockNumber *metrics.GaugeVec
+	metricRPCProviderGasLimit    *metrics.GaugeVec
+	metricTargetLatency          *metrics.SummaryVec
+	metricTargetErrorRate        *metrics.GaugeVec
+	metricTargetAvailability     *metrics.GaugeVec
+	metricProbes                 *metrics.CounterVec
+	metricFinalizedBlockNumber   *metrics.GaugeVec
 }
 
 func NewHealthcheckManager(config HealthcheckManagerConfig) *HealthcheckManager {
 	healthCheckers := []Healthchecker{}
 
+	registry := config.Metrics
+	if registry == nil {
+		registry = metrics.NewRegistry(metrics.Config{}, prometheus.DefaultRegisterer)
+	}
+
 	healthcheckManager := &HealthcheckManager{
-		metricRPCProviderInfo: promauto.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: "zeroex_rpc_gateway_provider_info",
-				Help: "Gas limit of a given provider",
-			}, []string{
-				"index",
-				"provider",
-			}),
-		metricRPCProviderStatus: promauto.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: "zeroex_rpc_gateway_provider_status",
-				Help: "Current status of a given provider by type. Type can be either healthy or tainted.",
-			}, []string{
-				"provider",
-				"type",
-			}),
-		metricResponseTime: promauto.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Name: "zeroex_rpc_gateway_healthcheck_response_duration_seconds",
-				Help: "Histogram of response time for Gateway Healthchecker in seconds",
-				Buckets: []float64{
-					.005,
-					.01,
-					.025,
-					.05,
-					.1,
-					.25,
-					.5,
-					1,
-					2.5,
-					5,
-					10,
-				},
-			}, []string{
-				"provider",
-				"method",
-			}),
-		metricRPCProviderBlockNumber: promauto.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: "zeroex_rpc_gateway_provider_block_number",
-				Help: "Block number of a given provider",
-			}, []string{
-				"provider",
-			}),
-		metricRPCProviderGasLimit: promauto.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: "zeroex_rpc_gateway_provider_gasLimit_number",
-				Help: "Gas limit of a given provider",
-			}, []string{
-				"provider",
-			}),
+		done: make(chan struct{}),
+		metricRPCProviderInfo: registry.NewGaugeVec(metrics.Desc{
+			Name:         "target_info",
+			Help:         "Index of a given target in the config",
+			Labels:       []string{"index", "target"},
+			Legacy:       "zeroex_rpc_gateway_provider_info",
+			LegacyLabels: []string{"index", "provider"},
+		}),
+		metricRPCProviderStatus: registry.NewGaugeVec(metrics.Desc{
+			Name:         "target_status",
+			Help:         "Current status of a given target by type. Type can be healthy, tainted, diverged or unknown.",
+			Labels:       []string{"target", "type"},
+			Legacy:       "zeroex_rpc_gateway_provider_status",
+			LegacyLabels: []string{"provider", "type"},
+		}),
+		metricResponseTime: registry.NewHistogramVec(metrics.Desc{
+			Name:         "healthcheck_response_duration_seconds",
+			Help:         "Histogram of response time for Gateway Healthchecker in seconds",
+			Labels:       []string{"target", "jsonrpc_method"},
+			Legacy:       "zeroex_rpc_gateway_healthcheck_response_duration_seconds",
+			LegacyLabels: []string{"provider", "method"},
+			Buckets: []float64{
+				.005,
+				.01,
+				.025,
+				.05,
+				.1,
+				.25,
+				.5,
+				1,
+				2.5,
+				5,
+				10,
+			},
+		}),
+		metricRPCProviderBlockNumber: registry.NewGaugeVec(metrics.Desc{
+			Name:         "target_block_number",
+			Help:         "Block number of a given target",
+			Labels:       []string{"target"},
+			Legacy:       "zeroex_rpc_gateway_provider_block_number",
+			LegacyLabels: []string{"provider"},
+		}),
+		metricRPCProviderGasLimit: registry.NewGaugeVec(metrics.Desc{
+			Name:         "target_gas_limit",
+			Help:         "Gas limit of a given target",
+			Labels:       []string{"target"},
+			Legacy:       "zeroex_rpc_gateway_provider_gasLimit_number",
+			LegacyLabels: []string{"provider"},
+		}),
+		metricTargetLatency: registry.NewSummaryVec(metrics.Desc{
+			Name:   "target_latency_seconds",
+			Help:   "Latency of the successful health checks and requests of a given target over the SLO window",
+			Labels: []string{"target", "source"},
+			Objectives: map[float64]float64{
+				0.5:  0.05,
+				0.95: 0.01,
+				0.99: 0.001,
+			},
+			MaxAge: sloWindowOrDefault(config.Config.SLO),
+		}),
+		metricTargetErrorRate: registry.NewGaugeVec(metrics.Desc{
+			Name:   "target_error_rate",
+			Help:   "Fraction of the requests to a given target that failed over the SLO window",
+			Labels: []string{"target"},
+		}),
+		metricTargetAvailability: registry.NewGaugeVec(metrics.Desc{
+			Name:   "target_availability",
+			Help:   "Fraction of the health checks of a given target that succeeded over the SLO window",
+			Labels: []string{"target"},
+		}),
+		metricProbes: registry.NewCounterVec(metrics.Desc{
+			Name:   "healthcheck_probes_total",
+			Help:   "Total number of health check probes of a given target by outcome",
+			Labels: []string{"target", "probe", "outcome"},
+		}),
+		metricFinalizedBlockNumber: registry.NewGaugeVec(metrics.Desc{
+			Name:   "target_finalized_block_number",
+			Help:   "Finalized block number of a given target",
+			Labels: []string{"target"},
+		}),
 	}
 
 	for _, target := range config.Targets {
+		chain := config.Chain.For(target)
 		healthchecker, err := NewHealthchecker(
 			RPCHealthcheckerConfig{
 				URL:              target.Connection.HTTP.URL,
 				Name:             target.Name,
-				Solana:			  config.Solana,
+				Archive:          target.Archive,
+				Capabilities:     config.Config.Capabilities,
+				Probes:           config.Config.Probes,
+				SolanaHealth:     config.Config.Solana,
+				TronHealth:       config.Config.Tron,
+				SuiHealth:        config.Config.Sui,
+				ClusterHead:      func() uint64 { return healthcheckManager.highestBlockNumberOf(chain) },
+				Chain:            chain,
 				Interval:         config.Config.Interval,
 				Timeout:          config.Config.Timeout,
 				FailureThreshold: config.Config.FailureThreshold,
 				SuccessThreshold: config.Config.SuccessThreshold,
 			})
+		if err != nil {
+			panic(err)
+		}
 
 		healthchecker.SetMetric(MetricBlockNumber, healthcheckManager.metricRPCProviderBlockNumber)
 		healthchecker.SetMetric(MetricGasLimit, healthcheckManager.metricRPCProviderGasLimit)
 		healthchecker.SetMetric(MetricResponseTime, healthcheckManager.metricResponseTime)
+		healthchecker.SetMetric(MetricProbes, healthcheckManager.metricProbes)
+		healthchecker.SetMetric(MetricFinalizedBlockNumber, healthcheckManager.metricFinalizedBlockNumber)
 
-		if err != nil {
-			panic(err)
-		}
+		slo := newSLOWindow(target.Name, config.Config.SLO, healthcheckManager.metricTargetLatency)
+		healthchecker.SetMetric(MetricSLO, slo)
+		healthcheckManager.slo = append(healthcheckManager.slo, slo)
+		healthcheckManager.chains = append(healthcheckManager.chains, chain)
 
 		healthCheckers = append(healthCheckers, healthchecker)
 	}
@@ -121,7 +185,10 @@ func (h *HealthcheckManager) runLoop(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			return nil
+		case <-h.done:
+			return nil
 		case <-ticker.C:
+			h.checkForks()
 			h.reportStatusMetrics()
 		}
 	}
@@ -131,27 +198,45 @@ func (h *HealthcheckManager) reportStatusMetrics() {
 	for _, healthchecker := range h.healthcheckers {
 		healthy := 0
 		tainted := 0
+		diverged := 0
+		unknown := 0
 		if healthchecker.IsHealthy() {
 			healthy = 1
 		}
+		if !healthchecker.Checked() {
+			unknown = 1
+		}
 		if healthchecker.IsTainted() {
 			tainted = 1
 		}
-		h.metricRPCProviderStatus.WithLabelValues(healthchecker.Name(), "healthy").Set(float64(healthy))
-		h.metricRPCProviderStatus.WithLabelValues(healthchecker.Name(), "tainted").Set(float64(tainted))
+		if healthchecker.IsDiverged() {
+			diverged = 1
+		}
+		h.metricRPCProviderStatus.Set(float64(healthy), healthchecker.Name(), "healthy")
+		h.metricRPCProviderStatus.Set(float64(tainted), healthchecker.Name(), "tainted")
+		h.metricRPCProviderStatus.Set(float64(diverged), healthchecker.Name(), "diverged")
+		h.metricRPCProviderStatus.Set(float64(unknown), healthchecker.Name(), "unknown")
+	}
+
+	for _, slo := range h.GetSLOs() {
+		h.metricTargetErrorRate.Set(slo.ErrorRate, slo.Target)
+		h.metricTargetAvailability.Set(slo.Availability, slo.Target)
 	}
 }
 
 func (h *HealthcheckManager) Start(ctx context.Context) error {
 	for index, healthChecker := range h.healthcheckers {
-		h.metricRPCProviderInfo.WithLabelValues(strconv.Itoa(index), healthChecker.Name()).Set(1)
+		h.metricRPCProviderInfo.Set(1, strconv.Itoa(index), healthChecker.Name())
 		go healthChecker.Start(ctx)
 	}
 
 	return h.runLoop(ctx)
 }
 
+// Stop ends the health checks of every target and closes their
+// connections.
 func (h *HealthcheckManager) Stop(ctx context.Context) error {
+	h.stopOnce.Do(func() { close(h.done) })
 	for _, healthChecker := range h.healthcheckers {
 		err := healthChecker.Stop(ctx)
 		if err != nil {
@@ -184,6 +269,40 @@ func (h *HealthcheckManager) GetTargetByName(name string) Healthchecker {
 	return nil
 }
 
+// ObserveRequest records the outcome of a request proxied to a target.
+func (h *HealthcheckManager) ObserveRequest(name string, latency time.Duration, failed bool) {
+	for idx, healthChecker := range h.healthcheckers {
+		if healthChecker.Name() == name && idx < len(h.slo) {
+			h.slo[idx].observeRequest(latency, failed)
+			return
+		}
+	}
+}
+
+// GetSLO returns the latency and error rate of a target over the rolling
+// window, nil for an unknown target.
+func (h *HealthcheckManager) GetSLO(name string) *TargetSLO {
+	for idx, healthChecker := range h.healthcheckers {
+		if healthChecker.Name() == name && idx < len(h.slo) {
+			slo := h.slo[idx].report()
+			return &slo
+		}
+	}
+
+	return nil
+}
+
+// GetSLOs returns the latency and error rate of every target over the
+// rolling window.
+func (h *HealthcheckManager) GetSLOs() []TargetSLO {
+	slos := make([]TargetSLO, 0, len(h.slo))
+	for _, slo := range h.slo {
+		slos = append(slos, slo.report())
+	}
+
+	return slos
+}
+
 func (h *HealthcheckManager) TaintTarget(name string) {
 	if healthChecker := h.GetTargetByName(name); healthChecker != nil {
 		healthChecker.Taint()
@@ -199,12 +318,90 @@ func (h *HealthcheckManager) IsTargetHealthy(name string) bool {
 	return false
 }
 
+// GetHighestBlockNumber returns the cluster head, the highest block number
+// known to a healthy target.
+func (h *HealthcheckManager) GetHighestBlockNumber() uint64 {
+	var head uint64
+	for _, healthChecker := range h.healthcheckers {
+		if healthChecker.IsHealthy() && healthChecker.BlockNumber() > head {
+			head = healthChecker.BlockNumber()
+		}
+	}
+
+	return head
+}
+
+// highestBlockNumberOf returns the cluster head of the targets of a chain
+// family, the heights of different chains cannot be compared.
+func (h *HealthcheckManager) highestBlockNumberOf(chain string) uint64 {
+	var head uint64
+	for i, healthChecker := range h.healthcheckers {
+		if i < len(h.chains) && h.chains[i] != chain {
+			continue
+		}
+		if healthChecker.IsHealthy() && healthChecker.BlockNumber() > head {
+			head = healthChecker.BlockNumber()
+		}
+	}
+
+	return head
+}
+
 func (h *HealthcheckManager) GetNextHealthyTargetIndex() int {
 	return h.GetNextHealthyTargetIndexExcluding([]uint{})
 }
 
+// GetLowestBlockNumber returns the highest block all healthy targets agree
+// on, that is the lowest block number reported by a healthy target.
+func (h *HealthcheckManager) GetLowestBlockNumber() uint64 {
+	var lowest uint64
+	for _, healthChecker := range h.healthcheckers {
+		blockNumber := healthChecker.BlockNumber()
+		if !healthChecker.IsHealthy() || blockNumber == 0 {
+			continue
+		}
+		if lowest == 0 || blockNumber < lowest {
+			lowest = blockNumber
+		}
+	}
+
+	return lowest
+}
+
+// TargetFilter narrows down the targets a request can be routed to.
+type TargetFilter func(index int, healthchecker Healthchecker) bool
+
 func (h *HealthcheckManager) GetNextHealthyTargetIndexExcluding(excludedIdx []uint) int {
+	idx := h.GetNextHealthyTargetIndexFiltered(excludedIdx, nil)
+	if idx < 0 {
+		idx = h.GetNextUnknownTargetIndexFiltered(excludedIdx, nil)
+	}
+	if idx < 0 && len(h.healthcheckers) > 0 {
+		// no healthy targets, we down:(
+		zap.L().Error("no more healthy targets")
+	}
+
+	return idx
+}
+
+// GetNextHealthyTargetIndexFiltered picks a random healthy target that is
+// not excluded and accepted by the filter.
+func (h *HealthcheckManager) GetNextHealthyTargetIndexFiltered(excludedIdx []uint, filter TargetFilter) int {
+	return h.nextTargetIndex(excludedIdx, filter, Healthchecker.IsHealthy)
+}
+
+// GetNextUnknownTargetIndexFiltered picks a random target whose health is
+// not known yet, the first round of health checks is in progress. It's the
+// last resort when no target is healthy, e.g. right after startup.
+func (h *HealthcheckManager) GetNextUnknownTargetIndexFiltered(excludedIdx []uint, filter TargetFilter) int {
+	return h.nextTargetIndex(excludedIdx, filter, isUnknown)
+}
+
+func isUnknown(healthchecker Healthchecker) bool {
+	return !healthchecker.Checked() && !healthchecker.IsTainted()
+}
 
+func (h *HealthcheckManager) nextTargetIndex(excludedIdx []uint, filter TargetFilter, accept func(Healthchecker) bool) int {
 	totalTargets := len(h.healthcheckers)
 	if totalTargets == 0 {
 		zap.L().Error("no targets")
@@ -216,13 +413,12 @@ func (h *HealthcheckManager) GetNextHealthyTargetIndexExcluding(excludedIdx []ui
 	for delta < totalTargets {
 		adjustedIndex := (idx + delta) % totalTargets
 		target := h.healthcheckers[adjustedIndex]
-		if !slices.Contains(excludedIdx, uint(adjustedIndex)) && target.IsHealthy() {
+		if !slices.Contains(excludedIdx, uint(adjustedIndex)) && accept(target) &&
+			(filter == nil || filter(adjustedIndex, target)) {
 			return adjustedIndex
 		}
 		delta++
 	}
 
-	// no healthy targets, we down:(
-	zap.L().Error("no more healthy targets")
 	return -1
 }
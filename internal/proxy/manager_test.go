@@ -1,7 +1,10 @@
 package proxy
 
 import (
+	"bytes"
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -21,13 +24,18 @@ func runAccumulatedTests(f func() int) float64 {
 func TestHealthcheckManager(t *testing.T) {
 	prometheus.DefaultRegisterer = prometheus.NewRegistry()
 
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x5f5e0ff"}`))
+	}))
+	defer fakeRPCServer.Close()
+
 	manager := NewHealthcheckManager(HealthcheckManagerConfig{
 		Targets: []TargetConfig{
 			{
 				Name: "Primary",
 				Connection: TargetConfigConnection{
 					HTTP: TargetConnectionHTTP{
-						URL: "https://cloudflare-eth.com",
+						URL: fakeRPCServer.URL,
 					},
 				},
 			},
@@ -35,7 +43,7 @@ func TestHealthcheckManager(t *testing.T) {
 				Name: "StandBy",
 				Connection: TargetConfigConnection{
 					HTTP: TargetConnectionHTTP{
-						URL: "https://cloudflare-eth.com",
+						URL: fakeRPCServer.URL,
 					},
 				},
 			},
@@ -52,6 +60,12 @@ func TestHealthcheckManager(t *testing.T) {
 	ctx := context.TODO()
 	go manager.Start(ctx)
 
+	// The targets are only selected once the first health check round
+	// found them healthy.
+	assert.Eventually(t, func() bool {
+		return manager.IsTargetHealthy("Primary") && manager.IsTargetHealthy("StandBy")
+	}, 5*time.Second, 10*time.Millisecond)
+
 	acc := runAccumulatedTests(func() int {
 		return manager.GetNextHealthyTargetIndex()
 	})
@@ -75,13 +89,18 @@ func TestHealthcheckManager(t *testing.T) {
 func TestGetNextHealthyTargetIndexExcluding(t *testing.T) {
 	prometheus.DefaultRegisterer = prometheus.NewRegistry()
 
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x5f5e0ff"}`))
+	}))
+	defer fakeRPCServer.Close()
+
 	manager := NewHealthcheckManager(HealthcheckManagerConfig{
 		Targets: []TargetConfig{
 			{
 				Name: "Primary",
 				Connection: TargetConfigConnection{
 					HTTP: TargetConnectionHTTP{
-						URL: "https://cloudflare-eth.com",
+						URL: fakeRPCServer.URL,
 					},
 				},
 			},
@@ -89,7 +108,7 @@ func TestGetNextHealthyTargetIndexExcluding(t *testing.T) {
 				Name: "Backup",
 				Connection: TargetConfigConnection{
 					HTTP: TargetConnectionHTTP{
-						URL: "https://cloudflare-eth.com",
+						URL: fakeRPCServer.URL,
 					},
 				},
 			},
@@ -108,6 +127,10 @@ func TestGetNextHealthyTargetIndexExcluding(t *testing.T) {
 	go manager.Start(ctx)
 	defer manager.Stop(ctx)
 
+	assert.Eventually(t, func() bool {
+		return manager.IsTargetHealthy("Primary") && manager.IsTargetHealthy("Backup")
+	}, 5*time.Second, 10*time.Millisecond)
+
 	accFromBoth := runAccumulatedTests(func() int {
 		return manager.GetNextHealthyTargetIndexExcluding([]uint{})
 	})
@@ -128,3 +151,135 @@ func TestGetNextHealthyTargetIndexExcluding(t *testing.T) {
 		return manager.GetNextHealthyTargetIndexExcluding([]uint{})
 	}))
 }
+
+func TestHealthcheckManagerStop(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer fakeRPCServer.Close()
+
+	manager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: []TargetConfig{
+			{
+				Name: "Primary",
+				Connection: TargetConfigConnection{
+					HTTP: TargetConnectionHTTP{
+						URL: fakeRPCServer.URL,
+					},
+				},
+			},
+		},
+		Config: HealthCheckConfig{
+			Interval: time.Hour,
+			Timeout:  time.Second,
+		},
+	})
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- manager.Start(context.Background())
+	}()
+	manager.TaintTarget("Primary")
+
+	assert.Nil(t, manager.Stop(context.Background()))
+	select {
+	case err := <-stopped:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the manager did not stop")
+	}
+	// Stopping twice is a no-op.
+	assert.Nil(t, manager.Stop(context.Background()))
+}
+
+func TestUnknownTargetsLastResort(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	manager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: []TargetConfig{
+			{Name: "Primary", Connection: TargetConfigConnection{HTTP: TargetConnectionHTTP{URL: "http://127.0.0.1:0"}}},
+			{Name: "Backup", Connection: TargetConfigConnection{HTTP: TargetConnectionHTTP{URL: "http://127.0.0.1:0"}}},
+		},
+	})
+
+	// The targets are unknown, not healthy, until they are checked.
+	assert.False(t, manager.IsTargetHealthy("Primary"))
+	assert.Equal(t, -1, manager.GetNextHealthyTargetIndexFiltered([]uint{}, nil))
+	assert.NotEqual(t, -1, manager.GetNextHealthyTargetIndexExcluding([]uint{}))
+
+	// A healthy target is preferred over an unknown one.
+	backup := manager.healthcheckers[1].(*RPCHealthchecker)
+	backup.checked = true
+	backup.isHealthy = true
+	assert.Equal(t, 1., runAccumulatedTests(func() int {
+		return manager.GetNextHealthyTargetIndexExcluding([]uint{})
+	}))
+
+	// A target known to be unhealthy is not a last resort.
+	backup.isHealthy = false
+	assert.Equal(t, 0., runAccumulatedTests(func() int {
+		return manager.GetNextHealthyTargetIndexExcluding([]uint{})
+	}))
+	manager.healthcheckers[0].(*RPCHealthchecker).checked = true
+	assert.Equal(t, -1, manager.GetNextHealthyTargetIndexExcluding([]uint{}))
+}
+
+func TestProxyUnknownTargetsLastResort(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer fakeRPCServer.Close()
+
+	rpcGatewayConfig := createConfig()
+	for _, name := range []string{"Healthy", "Unknown"} {
+		target := TargetConfig{Name: name}
+		target.Connection.HTTP.URL = fakeRPCServer.URL
+		rpcGatewayConfig.Targets = append(rpcGatewayConfig.Targets, target)
+	}
+	manager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	healthy := manager.healthcheckers[0].(*RPCHealthchecker)
+	healthy.checked = true
+	healthy.isHealthy = true
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, manager)
+
+	serve := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`))
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The target not checked yet is not used while another one is healthy.
+	for i := 0; i < 8; i++ {
+		assert.Equal(t, "Healthy", serve().Header().Get("X-Rpc-Provider"))
+	}
+
+	// It is once no target is known to be healthy.
+	healthy.isHealthy = false
+	rr := serve()
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "Unknown", rr.Header().Get("X-Rpc-Provider"))
+
+	// Checked and unhealthy, it is not used anymore.
+	manager.healthcheckers[1].(*RPCHealthchecker).checked = true
+	assert.Equal(t, http.StatusServiceUnavailable, serve().Code)
+}
+
+// markHealthy completes the first health check round of every target of
+// manager as a healthy one, the targets are unknown until then.
+func markHealthy(manager *HealthcheckManager) {
+	for _, healthChecker := range manager.healthcheckers {
+		rpcHealthchecker := healthChecker.(*RPCHealthchecker)
+		rpcHealthchecker.checked = true
+		rpcHealthchecker.isHealthy = true
+	}
+}
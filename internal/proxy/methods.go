@@ -0,0 +1,153 @@
+package proxy
+
+import "strings"
+
+// isStandardMethod reports whether method is part of the JSON-RPC API of
+// the supported chains, or one of its common extensions. The node management
+// APIs are included, they're what the firewall is usually there for.
+func isStandardMethod(method string) bool {
+	switch method {
+	case "admin_addPeer",
+		"admin_datadir",
+		"admin_nodeInfo",
+		"admin_peers",
+		"admin_removePeer",
+		"admin_startHTTP",
+		"admin_startWS",
+		"admin_stopHTTP",
+		"admin_stopWS",
+		"eth_accounts",
+		"eth_blobBaseFee",
+		"eth_blockNumber",
+		"eth_call",
+		"eth_chainId",
+		"eth_createAccessList",
+		"eth_estimateGas",
+		"eth_feeHistory",
+		"eth_gasPrice",
+		"eth_getBalance",
+		"eth_getBlockByHash",
+		"eth_getBlockByNumber",
+		"eth_getBlockReceipts",
+		"eth_getBlockTransactionCountByHash",
+		"eth_getBlockTransactionCountByNumber",
+		"eth_getCode",
+		"eth_getFilterChanges",
+		"eth_getFilterLogs",
+		"eth_getLogs",
+		"eth_getProof",
+		"eth_getStorageAt",
+		"eth_getTransactionByBlockHashAndIndex",
+		"eth_getTransactionByBlockNumberAndIndex",
+		"eth_getTransactionByHash",
+		"eth_getTransactionCount",
+		"eth_getTransactionReceipt",
+		"eth_getUncleByBlockHashAndIndex",
+		"eth_getUncleByBlockNumberAndIndex",
+		"eth_getUncleCountByBlockHash",
+		"eth_getUncleCountByBlockNumber",
+		"eth_maxPriorityFeePerGas",
+		"eth_newBlockFilter",
+		"eth_newFilter",
+		"eth_newPendingTransactionFilter",
+		"eth_sendRawTransaction",
+		"eth_sendPrivateTransaction",
+		"eth_sendTransaction",
+		"eth_sign",
+		"eth_signTransaction",
+		"eth_subscribe",
+		"eth_syncing",
+		"eth_uninstallFilter",
+		"eth_unsubscribe",
+		"net_listening",
+		"net_peerCount",
+		"net_version",
+		"web3_clientVersion",
+		"web3_sha3",
+		"debug_getBadBlocks",
+		"debug_setHead",
+		"debug_traceBlock",
+		"debug_traceBlockByHash",
+		"debug_traceBlockByNumber",
+		"debug_traceCall",
+		"debug_traceTransaction",
+		"miner_setEtherbase",
+		"miner_start",
+		"miner_stop",
+		"personal_listAccounts",
+		"personal_newAccount",
+		"personal_sendTransaction",
+		"personal_sign",
+		"personal_unlockAccount",
+		"txpool_content",
+		"txpool_inspect",
+		"txpool_status",
+		"trace_block",
+		"trace_call",
+		"trace_filter",
+		"trace_replayBlockTransactions",
+		"trace_replayTransaction",
+		"trace_transaction",
+		"getAccountInfo",
+		"getBalance",
+		"getBlock",
+		"getBlockHeight",
+		"getBlockTime",
+		"getHealth",
+		"getLatestBlockhash",
+		"getMultipleAccounts",
+		"getProgramAccounts",
+		"getSignatureStatuses",
+		"getSignaturesForAddress",
+		"getSlot",
+		"getTokenAccountBalance",
+		"getTokenAccountsByOwner",
+		"getTransaction",
+		"getVersion",
+		"sendTransaction",
+		"simulateTransaction",
+		"sui_getChainIdentifier",
+		"sui_getCheckpoint",
+		"sui_getLatestCheckpointSequenceNumber",
+		"sui_getObject",
+		"sui_getTransactionBlock",
+		"sui_multiGetObjects",
+		"suix_getBalance",
+		"suix_getCoins",
+		"suix_getOwnedObjects",
+		"suix_queryEvents":
+		return true
+	}
+
+	return false
+}
+
+// configuredMethods returns the methods named in the config, the patterns
+// of the firewall aside.
+func configuredMethods(config Config) map[string]bool {
+	methods := map[string]bool{}
+	add := func(names ...string) {
+		for _, name := range names {
+			if name != "" && !strings.ContainsAny(name, "*?[") {
+				methods[name] = true
+			}
+		}
+	}
+
+	add(config.Proxy.EmptyResultFailover.Methods...)
+	add(config.Proxy.Consensus.Methods...)
+	add(config.Proxy.PrivateTransactions.Methods...)
+	add(config.Proxy.Shadow.Methods...)
+	add(config.Proxy.Firewall.Allow...)
+	add(config.Proxy.Firewall.Deny...)
+	add(config.HealthChecks.Capabilities.Methods...)
+	for _, probe := range config.HealthChecks.Probes {
+		add(probe.Method)
+	}
+	for _, target := range config.Targets {
+		add(target.Firewall.Allow...)
+		add(target.Firewall.Deny...)
+	}
+
+	return methods
+}
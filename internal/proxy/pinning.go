@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"go.uber.org/zap"
+)
+
+const (
+	pinLatestToTarget  = "target"
+	pinLatestToCluster = "cluster"
+)
+
+// blockNumberFilter routes calls made against an explicit block number to
+// the targets that have already reached it.
+func (h *Proxy) blockNumberFilter(r *http.Request) TargetFilter {
+	if !h.config.Proxy.BlockPinning.Enabled {
+		return nil
+	}
+
+	body := GetRequestBodyFromContext(r)
+	if body == nil {
+		return nil
+	}
+
+	var required uint64
+	for _, request := range body.Requests {
+		if block, ok := request.BlockReference(); ok && block.HasNumber() && block.Number > required {
+			required = block.Number
+		}
+	}
+	if required == 0 {
+		return nil
+	}
+
+	return func(_ int, healthchecker Healthchecker) bool {
+		return healthchecker.BlockNumber() >= required
+	}
+}
+
+// pinLatestBlock rewrites the `latest` block tag to a block number known to
+// be available on peer, so a follow-up call for the same block routed to a
+// different target does not come back empty.
+func (h *Proxy) pinLatestBlock(r *http.Request, peer *HTTPTarget) {
+	body := GetRequestBodyFromContext(r)
+	if body == nil || len(body.Requests) == 0 {
+		return
+	}
+
+	var blockNumber uint64
+	switch h.config.Proxy.BlockPinning.Latest {
+	case pinLatestToTarget:
+		if healthchecker := h.healthcheckManager.GetTargetByName(peer.Config.Name); healthchecker != nil {
+			blockNumber = healthchecker.BlockNumber()
+		}
+	case pinLatestToCluster:
+		blockNumber = h.healthcheckManager.GetLowestBlockNumber()
+	default:
+		return
+	}
+
+	hasLatest := false
+	pinned := false
+	requests := make([]RPCRequest, len(body.Requests))
+	for i, request := range body.Requests {
+		requests[i] = request
+
+		block, ok := request.BlockReference()
+		if !ok || block.Tag != "latest" {
+			continue
+		}
+		hasLatest = true
+		if blockNumber == 0 {
+			continue
+		}
+
+		replaced, err := request.WithParam(blockParamIndex(request.Method), hexutil.EncodeUint64(blockNumber))
+		if err != nil {
+			zap.L().Debug("cannot pin latest block", zap.String("method", request.Method), zap.Error(err))
+			continue
+		}
+		requests[i] = replaced
+		pinned = true
+	}
+
+	if !hasLatest {
+		return
+	}
+
+	// The body of a previous attempt may have been pinned to a different
+	// target, start from the original body.
+	data := body.Raw
+	if pinned {
+		encoded, err := EncodeRPCRequests(requests, body.IsBatch)
+		if err != nil {
+			zap.L().Warn("cannot encode pinned request", zap.Error(err))
+		} else {
+			data = encoded
+		}
+	}
+
+	setRequestBody(r, data)
+}
+
+// setRequestBody replaces the body forwarded to the upstream. data is
+// always uncompressed.
+func setRequestBody(r *http.Request, data []byte) {
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	r.ContentLength = int64(len(data))
+	r.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	r.Header.Del("Content-Encoding")
+}
@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockPinning(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	var received []string
+	fakeRPCServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := new(bytes.Buffer)
+			body.ReadFrom(r.Body)
+			received = append(received, name+" "+body.String())
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+		}))
+	}
+	fakeRPC1Server := fakeRPCServer("Server1")
+	defer fakeRPC1Server.Close()
+	fakeRPC2Server := fakeRPCServer("Server2")
+	defer fakeRPC2Server.Close()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Proxy.BlockPinning = BlockPinningConfig{
+		Enabled: true,
+		Latest:  "cluster",
+	}
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "Server1",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPC1Server.URL,
+				},
+			},
+		},
+		{
+			Name: "Server2",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPC2Server.URL,
+				},
+			},
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	markHealthy(healthcheckManager)
+	healthcheckManager.healthcheckers[0].(*RPCHealthchecker).blockNumber = 0x10
+	healthcheckManager.healthcheckers[1].(*RPCHealthchecker).blockNumber = 0x20
+
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	serve := func(body string) {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(body))
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	// `latest` is pinned to the block both targets have reached.
+	serve(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000000000","latest"]}`)
+	assert.Len(t, received, 1)
+	assert.Contains(t, received[0], `"params":["0x0000000000000000000000000000000000000000","0x10"]`)
+
+	// Only Server2 has reached block 0x20.
+	received = nil
+	for i := 0; i < 8; i++ {
+		serve(`{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["0x20",false]}`)
+	}
+	for _, request := range received {
+		assert.Contains(t, request, "Server2 ")
+	}
+}
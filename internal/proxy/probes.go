@@ -0,0 +1,232 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// probeCheck is a check of a health check round.
+type probeCheck struct {
+	name   string
+	weight uint
+	run    func() error
+}
+
+// checkAndSetProbesHealth runs the liveness checks of the chain family and
+// the probes of the config one after another. A failed check adds its
+// weight to the consecutive failures, the node turns unhealthy once they
+// reach the failure threshold, and healthy again after as many rounds
+// without failures as the success threshold. The health of the node is
+// unknown until the first round, which sets it right away. A round still
+// running when the next one is due makes the next one skipped, the rounds
+// would count their failures and successes over each other.
+func (h *RPCHealthchecker) checkAndSetProbesHealth() {
+	if !h.probing.CompareAndSwap(false, true) {
+		zap.L().Warn("health check round still running, skipping", zap.String("rpcProvider", h.config.Name))
+		return
+	}
+	defer h.probing.Store(false)
+
+	checks := h.chain.LivenessChecks(h)
+	for _, probe := range h.config.Probes {
+		probe := probe
+		checks = append(checks, probeCheck{
+			name:   probeName(probe),
+			weight: probeWeight(probe),
+			run:    func() error { return h.runProbe(probe) },
+		})
+	}
+	if len(checks) == 0 {
+		h.mu.Lock()
+		if !h.checked {
+			h.checked = true
+			h.isHealthy = true
+		}
+		h.mu.Unlock()
+		return
+	}
+
+	var failures uint
+	for _, check := range checks {
+		outcome := "success"
+		if err := check.run(); err != nil {
+			zap.L().Warn("health check probe failed", zap.Error(err), zap.String("probe", check.name), zap.String("rpcProvider", h.config.Name))
+			outcome = "failure"
+			failures += check.weight
+		}
+		if h.metricProbes != nil {
+			h.metricProbes.Inc(h.config.Name, check.name, outcome)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.checked {
+		// The first round decides on its own, a node is not routed to for
+		// several rounds after startup nor kept until it fails enough.
+		h.checked = true
+		h.isHealthy = failures == 0
+		if failures > 0 {
+			h.probeFailures = failures
+		} else {
+			h.probeSuccesses = 1
+		}
+		return
+	}
+	if failures > 0 {
+		h.probeSuccesses = 0
+		h.probeFailures += failures
+		if h.probeFailures >= max(h.config.FailureThreshold, 1) {
+			h.isHealthy = false
+		}
+		return
+	}
+
+	h.probeFailures = 0
+	h.probeSuccesses++
+	if h.probeSuccesses >= max(h.config.SuccessThreshold, 1) {
+		h.isHealthy = true
+	}
+}
+
+func (h *RPCHealthchecker) runProbe(probe ProbeConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	params := make([]interface{}, 0, len(probe.Params))
+	for _, param := range probe.Params {
+		params = append(params, jsonValue(param))
+	}
+
+	var result json.RawMessage
+	start := time.Now()
+	err := h.client.CallContext(ctx, &result, probe.Method, params...)
+	if h.metricResponseTime != nil {
+		h.metricResponseTime.Observe(time.Since(start).Seconds(), h.config.Name, probe.Method)
+	}
+	if err != nil {
+		return err
+	}
+
+	return probe.Expect.check(result)
+}
+
+func probeName(probe ProbeConfig) string {
+	if probe.Name != "" {
+		return probe.Name
+	}
+
+	return probe.Method
+}
+
+func probeWeight(probe ProbeConfig) uint {
+	if probe.Weight == nil {
+		return 1
+	}
+
+	return *probe.Weight
+}
+
+// check reports why result does not match the expectation.
+func (e ProbeExpect) check(result json.RawMessage) error {
+	var value interface{}
+	if err := json.Unmarshal(result, &value); err != nil {
+		return errors.Wrap(err, "cannot decode result")
+	}
+
+	if e.Type != "" && jsonType(value) != e.Type {
+		return fmt.Errorf("expected a %s result, got %s", e.Type, result)
+	}
+
+	if e.Equals != nil {
+		expected, err := json.Marshal(jsonValue(e.Equals))
+		if err != nil {
+			return errors.Wrap(err, "cannot encode expected result")
+		}
+		var expectedValue interface{}
+		if err := json.Unmarshal(expected, &expectedValue); err != nil {
+			return errors.Wrap(err, "cannot decode expected result")
+		}
+		if !reflect.DeepEqual(value, expectedValue) {
+			return fmt.Errorf("expected %s, got %s", expected, result)
+		}
+	}
+
+	if e.Min != nil {
+		number, ok := quantity(value)
+		if !ok {
+			return fmt.Errorf("expected a quantity, got %s", result)
+		}
+		if number < *e.Min {
+			return fmt.Errorf("expected at least %v, got %s", *e.Min, result)
+		}
+	}
+
+	return nil
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// quantity reads a JSON number or a hex encoded quantity such as "0x1a".
+func quantity(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case string:
+		digits, found := strings.CutPrefix(value, "0x")
+		if !found {
+			return 0, false
+		}
+		number, err := strconv.ParseUint(digits, 16, 64)
+		if err != nil {
+			return 0, false
+		}
+		return float64(number), true
+	}
+
+	return 0, false
+}
+
+// jsonValue converts the maps decoded from the YAML config, keyed by
+// interface{}, to maps that can be encoded to JSON.
+func jsonValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			converted[fmt.Sprint(key)] = jsonValue(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, 0, len(value))
+		for _, item := range value {
+			converted = append(converted, jsonValue(item))
+		}
+		return converted
+	}
+
+	return value
+}
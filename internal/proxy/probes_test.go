@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestProbeExpect(t *testing.T) {
+	one := 1.0
+	for _, tc := range []struct {
+		expect ProbeExpect
+		result string
+		passes bool
+	}{
+		{ProbeExpect{}, `"anything"`, true},
+		{ProbeExpect{Type: "bool"}, `false`, true},
+		{ProbeExpect{Type: "bool"}, `{"startingBlock":"0x0"}`, false},
+		{ProbeExpect{Equals: false}, `false`, true},
+		{ProbeExpect{Equals: false}, `{"startingBlock":"0x0"}`, false},
+		{ProbeExpect{Equals: "ok"}, `"ok"`, true},
+		{ProbeExpect{Equals: map[interface{}]interface{}{"a": 1}}, `{"a":1}`, true},
+		{ProbeExpect{Type: "string", Min: &one}, `"0x19"`, true},
+		{ProbeExpect{Min: &one}, `"0x0"`, false},
+		{ProbeExpect{Min: &one}, `3`, true},
+		{ProbeExpect{Min: &one}, `"many"`, false},
+	} {
+		err := tc.expect.check(json.RawMessage(tc.result))
+		assert.Equal(t, tc.passes, err == nil, "%+v on %s: %v", tc.expect, tc.result, err)
+	}
+}
+
+func TestProbesHealth(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	syncing := false
+	var params []json.RawMessage
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request RPCRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		switch request.Method {
+		case "eth_syncing":
+			result, _ := json.Marshal(syncing)
+			w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(request.ID) + `,"result":` + string(result) + `}`))
+		case "eth_call":
+			// The gas left call of the liveness check comes with a state
+			// override.
+			var callParams []json.RawMessage
+			json.Unmarshal(request.Params, &callParams)
+			if len(callParams) == 2 {
+				params = callParams
+			}
+			w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(request.ID) + `,"result":"0x5f5e0ff"}`))
+		case "net_peerCount":
+			w.Write(NewRPCError(request.ID, rpcErrorMethodNotFound, "the method net_peerCount does not exist"))
+		}
+	}))
+	defer fakeRPCServer.Close()
+
+	var config HealthCheckConfig
+	err := yaml.Unmarshal([]byte(`
+failureThreshold: 2
+successThreshold: 2
+probes:
+  - method: eth_syncing
+    expect:
+      equals: false
+  - name: call
+    method: eth_call
+    params: [{to: "0x0000000000000000000000000000000000000000", data: "0x"}, latest]
+  - method: net_peerCount
+    weight: 0
+`), &config)
+	assert.Nil(t, err)
+
+	healthchecker, err := NewHealthchecker(RPCHealthcheckerConfig{
+		URL:              fakeRPCServer.URL,
+		Name:             "Server1",
+		Timeout:          time.Second,
+		Probes:           config.Probes,
+		FailureThreshold: config.FailureThreshold,
+		SuccessThreshold: config.SuccessThreshold,
+	})
+	assert.Nil(t, err)
+	rpcHealthchecker := healthchecker.(*RPCHealthchecker)
+
+	// The failure of an unweighted probe is only reported.
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.True(t, healthchecker.IsHealthy())
+	assert.Len(t, params, 2)
+	assert.JSONEq(t, `{"to":"0x0000000000000000000000000000000000000000","data":"0x"}`, string(params[0]))
+
+	syncing = true
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.True(t, healthchecker.IsHealthy())
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.False(t, healthchecker.IsHealthy())
+
+	syncing = false
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.False(t, healthchecker.IsHealthy())
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.True(t, healthchecker.IsHealthy())
+}
+
+func TestProbesRoundsDoNotOverlap(t *testing.T) {
+	received := &atomic.Int32{}
+	release := make(chan struct{})
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request RPCRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		received.Add(1)
+		<-release
+		w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(request.ID) + `,"result":false}`))
+	}))
+	defer fakeRPCServer.Close()
+
+	healthchecker, err := NewHealthchecker(RPCHealthcheckerConfig{
+		URL:     fakeRPCServer.URL,
+		Name:    "Server1",
+		Timeout: time.Second,
+		Probes:  []ProbeConfig{{Method: "eth_syncing"}},
+	})
+	assert.Nil(t, err)
+	rpcHealthchecker := healthchecker.(*RPCHealthchecker)
+
+	done := make(chan struct{})
+	go func() {
+		rpcHealthchecker.checkAndSetProbesHealth()
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return received.Load() == 1 }, time.Second, time.Millisecond)
+
+	// The round due while the first one is running is skipped.
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.Equal(t, int32(1), received.Load())
+
+	close(release)
+	<-done
+	assert.True(t, healthchecker.Checked())
+}
@@ -2,17 +2,22 @@ package proxy
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httputil"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/0xProject/rpc-gateway/internal/metrics"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -20,67 +25,126 @@ type HTTPTarget struct {
 	Config  TargetConfig
 	Proxy   *httputil.ReverseProxy
 	WsProxy *httputil.ReverseProxy
+	Client  *http.Client
 }
 
 type Proxy struct {
 	config             Config
 	targets            []*HTTPTarget
 	healthcheckManager *HealthcheckManager
-
-	metricResponseTime   *prometheus.HistogramVec
-	metricRequestErrors  *prometheus.CounterVec
-	metricResponseStatus *prometheus.CounterVec
-	metricResponseErrors *prometheus.CounterVec
+	sessions           *stickySessions
+	accessLog          *accessLogger
+	debugRules         debugRules
+	// when the proxy was created, the first round of health checks starts
+	// along.
+	startedAt time.Time
+	// methods named in the config, used as metric labels.
+	methods map[string]bool
+
+	// websocket sessions in flight, they outlive the HTTP server once
+	// upgraded. Cancelling websocketsCtx closes them.
+	websockets      sync.WaitGroup
+	websocketsCtx   context.Context
+	closeWebsockets context.CancelFunc
+
+	metricResponseTime    *metrics.HistogramVec
+	metricRequestErrors   *metrics.CounterVec
+	metricResponseStatus  *metrics.CounterVec
+	metricResponseErrors  *metrics.CounterVec
+	metricFirewallBlocked *metrics.CounterVec
+
+	metricConsensus              *metrics.CounterVec
+	metricConsensusDisagreements *metrics.CounterVec
+	metricShadowRequests         *metrics.CounterVec
+	metricShadowLatencyDiff      *metrics.HistogramVec
 }
 
 func NewProxy(proxyConfig Config, healthCheckManager *HealthcheckManager) *Proxy {
+	registry := proxyConfig.Metrics
+	if registry == nil {
+		registry = metrics.NewRegistry(metrics.Config{}, prometheus.DefaultRegisterer)
+	}
+
+	websocketsCtx, closeWebsockets := context.WithCancel(context.Background())
 	proxy := &Proxy{
 		config:             proxyConfig,
+		websocketsCtx:      websocketsCtx,
+		closeWebsockets:    closeWebsockets,
 		healthcheckManager: healthCheckManager,
-		metricResponseTime: promauto.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Name: "zeroex_rpc_gateway_request_duration_seconds",
-				Help: "Histogram of response time for Gateway in seconds",
-				Buckets: []float64{
-					.025,
-					.05,
-					.1,
-					.25,
-					.5,
-					1,
-					2.5,
-					5,
-					10,
-					15,
-					20,
-					25,
-					30,
-				},
-			}, []string{
-				"provider",
-				"method",
-			}),
-		metricRequestErrors: promauto.NewCounterVec(
-			prometheus.CounterOpts{
-				Name: "zeroex_rpc_gateway_request_errors_handled_total",
-				Help: "The total number of request errors handled by gateway",
-			}, []string{
-				"provider",
-				"type",
-			}),
-		metricResponseStatus: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: "zeroex_rpc_gateway_target_response_status_total",
-			Help: "Total number of responses with a statuscode label",
-		}, []string{
-			"provider",
-			"status_code",
+		startedAt:          time.Now(),
+		methods:            configuredMethods(proxyConfig),
+		sessions:           newStickySessions(proxyConfig.Proxy.Stickiness),
+		accessLog:          newAccessLogger(proxyConfig.Proxy.AccessLog),
+		metricResponseTime: registry.NewHistogramVec(metrics.Desc{
+			Name:         "request_duration_seconds",
+			Help:         "Histogram of response time for Gateway in seconds",
+			Labels:       []string{"target", "jsonrpc_method"},
+			Legacy:       "zeroex_rpc_gateway_request_duration_seconds",
+			LegacyLabels: []string{"provider", "method"},
+			Buckets: []float64{
+				.025,
+				.05,
+				.1,
+				.25,
+				.5,
+				1,
+				2.5,
+				5,
+				10,
+				15,
+				20,
+				25,
+				30,
+			},
+		}),
+		metricRequestErrors: registry.NewCounterVec(metrics.Desc{
+			Name:         "request_errors_handled_total",
+			Help:         "The total number of request errors handled by gateway",
+			Labels:       []string{"target", "outcome"},
+			Legacy:       "zeroex_rpc_gateway_request_errors_handled_total",
+			LegacyLabels: []string{"provider", "type"},
+		}),
+		metricResponseStatus: registry.NewCounterVec(metrics.Desc{
+			Name:         "target_response_status_total",
+			Help:         "Total number of responses with a statuscode label",
+			Labels:       []string{"target", "status_code"},
+			Legacy:       "zeroex_rpc_gateway_target_response_status_total",
+			LegacyLabels: []string{"provider", "status_code"},
+		}),
+		metricResponseErrors: registry.NewCounterVec(metrics.Desc{
+			Name:         "target_response_errors_handled_total",
+			Help:         "Total number of responses with an error",
+			Labels:       []string{"target", "outcome"},
+			Legacy:       "allbridge_rpc_gateway_target_response_errors_handled_total",
+			LegacyLabels: []string{"provider", "error_message"},
+		}),
+		metricFirewallBlocked: registry.NewCounterVec(metrics.Desc{
+			Name:         "firewall_blocked_total",
+			Help:         "Total number of calls rejected by the method firewall by method",
+			Labels:       []string{"jsonrpc_method"},
+			Legacy:       "allbridge_rpc_gateway_firewall_blocked_total",
+			LegacyLabels: []string{"method"},
+		}),
+		metricConsensus: registry.NewCounterVec(metrics.Desc{
+			Name:   "consensus_requests_total",
+			Help:   "Total number of calls served in consensus mode by outcome",
+			Labels: []string{"outcome"},
 		}),
-		metricResponseErrors: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: "allbridge_rpc_gateway_target_response_errors_handled_total",
-			Help: "Total number of responses with an error",
-		}, []string{
-			"provider",
-			"error_message",
+		metricConsensusDisagreements: registry.NewCounterVec(metrics.Desc{
+			Name:   "consensus_disagreements_total",
+			Help:   "Total number of consensus calls a given target did not answer like the quorum",
+			Labels: []string{"target", "outcome"},
+		}),
+		metricShadowRequests: registry.NewCounterVec(metrics.Desc{
+			Name:   "shadow_requests_total",
+			Help:   "Total number of requests mirrored to a given shadow target by outcome",
+			Labels: []string{"target", "outcome"},
+		}),
+		metricShadowLatencyDiff: registry.NewHistogramVec(metrics.Desc{
+			Name:    "shadow_latency_diff_seconds",
+			Help:    "Latency of a given shadow target minus the one of the target that served the request",
+			Labels:  []string{"target"},
+			Buckets: []float64{-2.5, -1, -.5, -.25, -.1, -.05, 0, .05, .1, .25, .5, 1, 2.5},
 		}),
 	}
 
@@ -93,9 +157,15 @@ func NewProxy(proxyConfig Config, healthCheckManager *HealthcheckManager) *Proxy
 	return proxy
 }
 
-func (h *Proxy) doModifyResponse(config TargetConfig, exceptions []Exception) func(*http.Response) error {
+func (h *Proxy) doModifyResponse(config TargetConfig, index uint, exceptions []Exception) func(*http.Response) error {
 	return func(resp *http.Response) error {
-		h.metricResponseStatus.WithLabelValues(config.Name, strconv.Itoa(resp.StatusCode)).Inc()
+		h.metricResponseStatus.Inc(config.Name, strconv.Itoa(resp.StatusCode))
+
+		span := trace.SpanFromContext(resp.Request.Context())
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+		GetRequestHistoryFromContext(resp.Request).last().setStatus(resp.StatusCode)
+		dropRoutingHeaders(resp.Header)
+		h.logDebugResponse(resp, config)
 
 		switch {
 		// Here's the thing. A different provider may response with a
@@ -116,7 +186,7 @@ func (h *Proxy) doModifyResponse(config TargetConfig, exceptions []Exception) fu
 			// this code generates a fallback to backup provider.
 			//
 			zap.L().Warn("rate limited", zap.String("provider", config.Name))
-			h.metricResponseErrors.WithLabelValues(config.Name, "rate limited").Inc()
+			h.metricResponseErrors.Inc(config.Name, "rate limited")
 
 			return errors.New("rate limited")
 
@@ -124,7 +194,7 @@ func (h *Proxy) doModifyResponse(config TargetConfig, exceptions []Exception) fu
 			// this code generates a fallback to backup provider.
 			//
 			zap.L().Warn("request entity too large", zap.String("provider", config.Name))
-			h.metricResponseErrors.WithLabelValues(config.Name, "request entity too large").Inc()
+			h.metricResponseErrors.Inc(config.Name, "request entity too large")
 
 			return errors.New("request entity too large")
 
@@ -132,7 +202,7 @@ func (h *Proxy) doModifyResponse(config TargetConfig, exceptions []Exception) fu
 			// this code generates a fallback to backup provider.
 			//
 			zap.L().Warn("server error", zap.String("provider", config.Name))
-			h.metricResponseErrors.WithLabelValues(config.Name, "server error").Inc()
+			h.metricResponseErrors.Inc(config.Name, "server error")
 
 			return errors.New("server error")
 
@@ -140,7 +210,7 @@ func (h *Proxy) doModifyResponse(config TargetConfig, exceptions []Exception) fu
 			// this code generates a fallback to backup provider.
 			//
 			zap.L().Warn("access forbidden", zap.String("provider", config.Name))
-			h.metricResponseErrors.WithLabelValues(config.Name, "access forbidden").Inc()
+			h.metricResponseErrors.Inc(config.Name, "access forbidden")
 
 			return errors.New("access forbidden")
 		}
@@ -150,29 +220,107 @@ func (h *Proxy) doModifyResponse(config TargetConfig, exceptions []Exception) fu
 			return err
 		}
 
-		for _, exception := range exceptions {
-			if strings.Contains(bodyString, exception.Match) {
-				message := exception.Message
-				if message == "" {
-					message = exception.Match
-				}
-				h.metricResponseErrors.WithLabelValues(config.Name, message).Inc()
+		if message, ok := matchException(bodyString, exceptions); ok {
+			h.metricResponseErrors.Inc(config.Name, message)
+			span.SetAttributes(attribute.String("rpc.exception", message))
 
-				return errors.New(message)
-			}
+			return errors.New(message)
+		}
+
+		if h.shouldRetryEmptyResult(resp.Request, bodyString, index) {
+			zap.L().Debug("empty result", zap.String("provider", config.Name))
+			h.metricResponseErrors.Inc(config.Name, "empty result")
+
+			return errors.New("empty result")
 		}
 
 		return nil
 	}
 }
 
+// matchException returns the message of the first exception found in body.
+func matchException(body string, exceptions []Exception) (string, bool) {
+	for _, exception := range exceptions {
+		if strings.Contains(body, exception.Match) {
+			message := exception.Message
+			if message == "" {
+				message = exception.Match
+			}
+
+			return message, true
+		}
+	}
+
+	return "", false
+}
+
+// shouldRetryEmptyResult reports whether a null/empty result returned by a
+// lagging node should be retried on a different target. A call made against
+// an explicit block number is only retried when the block is at or below the
+// cluster head, otherwise the empty result is legit.
+func (h *Proxy) shouldRetryEmptyResult(r *http.Request, body string, index uint) bool {
+	config := h.config.Proxy.EmptyResultFailover
+	if len(config.Methods) == 0 || r == nil {
+		return false
+	}
+
+	request, ok := GetRequestBodyFromContext(r).SingleRequest()
+	if !ok || !slices.Contains(config.Methods, request.Method) {
+		return false
+	}
+
+	response, err := ParseRPCResponse([]byte(body))
+	if err != nil || !response.IsEmptyResult() {
+		return false
+	}
+
+	if block, ok := request.BlockReference(); ok && block.HasNumber() {
+		head := h.clusterHead(h.targetGroups(r)[0])()
+		if head == 0 || block.Number > head {
+			return false
+		}
+	}
+
+	retries := config.Retries
+	if retries == 0 {
+		retries = 1
+	}
+	visitedTargets := GetVisitedTargetsFromContext(r)
+	if uint(len(visitedTargets)) >= retries {
+		return false
+	}
+
+	// When there's nothing left to try, the empty result is returned to the
+	// client rather than a 503.
+	excludedIndexes := append(append([]uint{index}, visitedTargets...), h.GetDisabledTargetIndexes()...)
+
+	return h.hasNextTargetFor(r, excludedIndexes)
+}
+
+// hasNextTargetFor reports whether GetNextTargetFor finds a target for
+// request, without picking it.
+func (h *Proxy) hasNextTargetFor(r *http.Request, indexes []uint) bool {
+	for _, group := range h.targetGroups(r) {
+		required := h.requiredFilter(r, group)
+		if h.healthcheckManager.GetNextHealthyTargetIndexFiltered(indexes, required) >= 0 ||
+			h.healthcheckManager.GetNextUnknownTargetIndexFiltered(indexes, required) >= 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (h *Proxy) doErrorHandler(config TargetConfig, index uint) func(http.ResponseWriter, *http.Request, error) {
 	return func(w http.ResponseWriter, r *http.Request, e error) {
+		endAttemptSpan(r, e)
+		GetRequestHistoryFromContext(r).last().finish(e)
+
 		// The client canceled the request (e.g. 0x API has a 5s timeout for RPC request)
 		// we stop here as it doesn't make sense to retry/reroute anymore.
 		// Also, we don't want to observe a client-canceled request as a failure
 		if errors.Is(e, context.Canceled) {
-			h.metricRequestErrors.WithLabelValues(config.Name, "client_closed_connection").Inc()
+			h.metricRequestErrors.Inc(config.Name, "client_closed_connection")
 
 			return
 		}
@@ -185,9 +333,12 @@ func (h *Proxy) doErrorHandler(config TargetConfig, index uint) func(http.Respon
 		}
 
 		zap.L().Warn("handling a failed request", zap.String("provider", config.Name), zap.Error(e))
+		if attempt := GetRequestHistoryFromContext(r).last(); attempt != nil {
+			h.healthcheckManager.ObserveRequest(config.Name, attempt.elapsed, true)
+		}
 
 		// route the request to a different target
-		h.metricRequestErrors.WithLabelValues(config.Name, "rerouted").Inc()
+		h.metricRequestErrors.Inc(config.Name, "rerouted")
 		visitedTargets := GetVisitedTargetsFromContext(r)
 
 		// add the current target to the VisitedTargets slice to exclude it when selecting
@@ -208,11 +359,15 @@ func (h *Proxy) AddTarget(target TargetConfig, index uint, exceptions []Exceptio
 		return err
 	}
 
+	// The errors known to the chain family are retried like the ones of
+	// the config.
+	exceptions = append(slices.Clip(exceptions), h.config.Chain.chainFamilyOf(target).Exceptions()...)
+
 	// NOTE: any error returned from ModifyResponse will be handled by
 	// ErrorHandler
 	// proxy.ModifyResponse = h.doModifyResponse(config)
 	//
-	proxy.ModifyResponse = h.doModifyResponse(target, exceptions) // nolint:bodyclose
+	proxy.ModifyResponse = h.doModifyResponse(target, index, exceptions) // nolint:bodyclose
 	proxy.ErrorHandler = h.doErrorHandler(target, index)
 
 	h.targets = append(
@@ -221,13 +376,17 @@ func (h *Proxy) AddTarget(target TargetConfig, index uint, exceptions []Exceptio
 			Config:  target,
 			Proxy:   proxy,
 			WsProxy: wsProxy,
+			Client:  &http.Client{Transport: proxy.Transport},
 		})
 
 	return nil
 }
 
 func (h *Proxy) GetNextTarget() *HTTPTarget {
-	idx := h.healthcheckManager.GetNextHealthyTargetIndex()
+	idx := h.healthcheckManager.GetNextHealthyTargetIndexFiltered([]uint{}, h.shadowFilter())
+	if idx < 0 {
+		idx = h.healthcheckManager.GetNextUnknownTargetIndexFiltered([]uint{}, h.shadowFilter())
+	}
 
 	if idx < 0 {
 		return nil
@@ -237,7 +396,10 @@ func (h *Proxy) GetNextTarget() *HTTPTarget {
 }
 
 func (h *Proxy) GetNextTargetExcluding(indexes []uint) *HTTPTarget {
-	idx := h.healthcheckManager.GetNextHealthyTargetIndexExcluding(indexes)
+	idx := h.healthcheckManager.GetNextHealthyTargetIndexFiltered(indexes, h.shadowFilter())
+	if idx < 0 {
+		idx = h.healthcheckManager.GetNextUnknownTargetIndexFiltered(indexes, h.shadowFilter())
+	}
 
 	if idx < 0 {
 		return nil
@@ -246,6 +408,114 @@ func (h *Proxy) GetNextTargetExcluding(indexes []uint) *HTTPTarget {
 	return h.targets[idx]
 }
 
+// GetNextTargetFor picks the target for request. The target must belong to
+// the group the request is routed to. The other constraints derived from the
+// request are preferences: when no healthy target satisfies them, any healthy
+// target of the group is used, and a target not checked yet when none is
+// healthy.
+func (h *Proxy) GetNextTargetFor(r *http.Request, indexes []uint) *HTTPTarget {
+	for _, group := range h.targetGroups(r) {
+		if target := h.getNextTargetInGroup(r, indexes, group); target != nil {
+			return target
+		}
+	}
+
+	zap.L().Error("no more healthy targets")
+	return nil
+}
+
+func (h *Proxy) getNextTargetInGroup(r *http.Request, indexes []uint, group string) *HTTPTarget {
+	required := h.requiredFilter(r, group)
+	preferred := allOf(required, h.requestFilter(r))
+
+	if key := h.stickyKey(r); key != "" {
+		idx := h.stickyTargetIndex(key, indexes, preferred)
+		if idx < 0 {
+			idx = h.stickyTargetIndex(key, indexes, required)
+		}
+		if idx >= 0 {
+			h.sessions.record(key, h.targets[idx].Config.Name)
+			return h.targets[idx]
+		}
+	}
+
+	for _, filter := range []TargetFilter{preferred, required} {
+		if idx := h.healthcheckManager.GetNextHealthyTargetIndexFiltered(indexes, filter); idx >= 0 {
+			return h.targets[idx]
+		}
+	}
+
+	// Targets not checked yet are the last resort, no target of the group
+	// is known to be healthy.
+	for _, filter := range []TargetFilter{preferred, required} {
+		if idx := h.healthcheckManager.GetNextUnknownTargetIndexFiltered(indexes, filter); idx >= 0 {
+			return h.targets[idx]
+		}
+	}
+
+	return nil
+}
+
+// requiredFilter accepts the targets of group allowed to serve request.
+func (h *Proxy) requiredFilter(r *http.Request, group string) TargetFilter {
+	return allOf(h.groupFilter(group), h.firewallFilter(r))
+}
+
+// requestFilter combines the routing preferences that apply to request.
+func (h *Proxy) requestFilter(r *http.Request) TargetFilter {
+	return allOf(
+		h.blockNumberFilter(r),
+		h.logsRangeFilter(r),
+		h.archiveFilter(r),
+		capabilityFilter(r),
+	)
+}
+
+// allOf accepts a target when all of the non-nil filters accept it.
+func allOf(filters ...TargetFilter) TargetFilter {
+	return func(index int, healthchecker Healthchecker) bool {
+		for _, filter := range filters {
+			if filter != nil && !filter(index, healthchecker) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// DrainWebsockets waits for the websocket sessions in flight to end, and
+// closes the remaining ones once ctx is done. The servers must not accept
+// connections anymore.
+func (h *Proxy) DrainWebsockets(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.websockets.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		h.closeWebsockets()
+		<-done
+
+		return ctx.Err()
+	}
+}
+
+// ServesWebsocketsOnNextPort reports whether a target serves websockets on
+// the port next to the HTTP one, the gateway then listens there too.
+func (h *Proxy) ServesWebsocketsOnNextPort() bool {
+	for _, target := range h.targets {
+		if target.WsProxy != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (h *Proxy) GetNextTargetName() string {
 	return h.GetNextTarget().Config.Name
 }
@@ -270,38 +540,141 @@ func (h *Proxy) GetTargetConfigs() []TargetConfig {
 
 func (h *Proxy) GetTargetConfigByName(name string) *TargetConfig {
 	for _, target := range h.targets {
-	    if target.Config.Name == name {
-            return &target.Config
-	    }
+		if target.Config.Name == name {
+			return &target.Config
+		}
 	}
 	return nil
 }
 
+// withDecodedBody decodes the JSON-RPC body once, on the first pass, and
+// keeps it in the request context for the reroutes. The body forwarded to
+// the upstream is left untouched.
+func withDecodedBody(r *http.Request) *http.Request {
+	if isWebsocketHandshake(r) || r.Body == nil || GetRequestBodyFromContext(r) != nil {
+		return r
+	}
+
+	data, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		zap.L().Warn("cannot read request body", zap.Error(err))
+		return r
+	}
+
+	body := &RequestBody{Raw: data}
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		uncompressed, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			zap.L().Debug("cannot decompress request body", zap.Error(err))
+			return r
+		}
+		if body.Raw, err = io.ReadAll(uncompressed); err != nil {
+			zap.L().Debug("cannot decompress request body", zap.Error(err))
+			return r
+		}
+	}
+
+	body.Requests, body.IsBatch, err = ParseRPCRequests(body.Raw)
+	if err != nil {
+		zap.L().Debug("cannot decode request body", zap.Error(err))
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), DecodedRequest, body))
+}
+
 func (h *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r = withDecodedBody(r)
+
+	// Reroutes go through here again, within the span and the history of
+	// the first pass.
+	if GetRequestHistoryFromContext(r) == nil {
+		var span trace.Span
+		r, span = startRequestSpan(r)
+		defer span.End()
+
+		history := newRequestHistory(r)
+		r = r.WithContext(context.WithValue(r.Context(), History, history))
+		setRoutingHeaders(w, history)
+		if h.accessLog != nil {
+			recorder := newResponseRecorder(w, h.accessLog.bodySize())
+			w = recorder
+			defer h.accessLog.log(r, history, recorder)
+		}
+		if shadows := h.shadowTargets(r); len(shadows) > 0 {
+			recorder := newResponseRecorder(w, h.config.Proxy.Shadow.maxBodySize())
+			w = recorder
+			defer h.mirror(r, history, recorder, shadows)
+		}
+	}
+
+	if h.rejectBlockedMethods(w, r) {
+		return
+	}
+
+	if request, ok := GetRequestBodyFromContext(r).SingleRequest(); ok && h.isBroadcast(request) {
+		h.serveBroadcast(w, r, request)
+		return
+	}
+
+	if request, ok := GetRequestBodyFromContext(r).SingleRequest(); ok && h.isConsensus(r, request) {
+		h.serveConsensus(w, r, request)
+		return
+	}
+
+	if request, ok := GetRequestBodyFromContext(r).SingleRequest(); ok && h.serveSplitLogs(w, r, request) {
+		return
+	}
+
 	visitedTargets := GetVisitedTargetsFromContext(r)
 	disabledTargets := h.GetDisabledTargetIndexes()
-    excludedIndexes := append(visitedTargets, disabledTargets...)
+	excludedIndexes := append(visitedTargets, disabledTargets...)
 
-	peer := h.GetNextTargetExcluding(excludedIndexes)
+	peer := h.GetNextTargetFor(r, excludedIndexes)
+	if peer != nil && isWebsocketHandshake(r) && peer.WsProxy == nil && h.rejectUninspectedHandshake(w, r) {
+		return
+	}
 	if peer != nil {
+		h.pinLatestBlock(r, peer)
+
+		r, span := startAttemptSpan(r, peer)
+		history := GetRequestHistoryFromContext(r)
+		attempt := history.begin(peer.Config.Name)
+		setRoutingHeaders(w, history)
+		h.logDebugRequest(r, peer.Config.Name)
+		// Failed attempts are ended by the error handler, ending twice is
+		// a no-op.
+		defer span.End()
+		defer attempt.finish(nil)
+
 		start := time.Now()
-		isWS := r.Header.Get("Upgrade") != "" && peer.WsProxy != nil
-		w.Header().Set("X-Rpc-Provider", peer.Config.Name)
+		isWS := isWebsocketHandshake(r) && peer.WsProxy != nil
 		//if isWS {
 		//	w.Header().Set("X-Rpc-Target-Url", peer.Config.Connection.WS.URL)
 		//} else {
 		//	w.Header().Set("X-Rpc-Target-Url", peer.Config.Connection.HTTP.URL)
 		//}
 		if isWS {
-			peer.WsProxy.ServeHTTP(w, r)
+			h.websockets.Add(1)
+			ctx, cancel := context.WithCancel(r.Context())
+			stop := context.AfterFunc(h.websocketsCtx, cancel)
+			peer.WsProxy.ServeHTTP(w, r.WithContext(ctx))
+			stop()
+			cancel()
+			h.websockets.Done()
 		} else {
 			peer.Proxy.ServeHTTP(w, r)
 		}
-		duration := time.Since(start)
-		h.metricResponseTime.WithLabelValues(peer.Config.Name, r.Method).Observe(duration.Seconds())
+		h.observeResponseTime(r, peer.Config.Name, time.Since(start))
+		// Failed attempts are observed by the error handler, before the
+		// reroute.
+		if attempt.finish(nil); attempt.Error == "" && !isWS {
+			h.healthcheckManager.ObserveRequest(peer.Config.Name, attempt.elapsed, false)
+		}
 
 		return
 	}
 
+	setRoutingHeaders(w, GetRequestHistoryFromContext(r))
 	http.Error(w, "Service not available", http.StatusServiceUnavailable)
 }
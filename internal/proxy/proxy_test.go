@@ -10,7 +10,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/0xProject/rpc-gateway/internal/metrics"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -26,7 +28,7 @@ func createConfig() Config {
 			SuccessThreshold: 0,
 		},
 		Targets: []TargetConfig{},
-		Solana:  false,
+		Chain:   ChainConfig{Default: ChainEVM},
 	}
 }
 
@@ -237,11 +239,11 @@ func TestHttpFailoverProxyRerouteWsRequests(t *testing.T) {
 			},
 		},
 	}
-	rpcGatewayConfig.Solana = true
+	rpcGatewayConfig.Chain = ChainConfig{Default: ChainSolana}
 	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
 		Targets: rpcGatewayConfig.Targets,
 		Config:  rpcGatewayConfig.HealthChecks,
-		Solana:  true,
+		Chain:   rpcGatewayConfig.Chain,
 	})
 
 	// Setup HttpFailoverProxy but not starting the HealthCheckManager
@@ -251,6 +253,7 @@ func TestHttpFailoverProxyRerouteWsRequests(t *testing.T) {
 
 	requestBody := bytes.NewBufferString(`{"this_is": "body"}`)
 	req, err := http.NewRequest("POST", "/", requestBody)
+	req.Header.Add("Connection", "Upgrade")
 	req.Header.Add("Upgrade", "WebSocket")
 	assert.Nil(t, err)
 
@@ -538,3 +541,213 @@ func TestHTTPFailoverProxyWhenPrimaryProviderDisabled(t *testing.T) {
 		t.Errorf("server returned unexpected body: got '%v' want '%v'", rr.Body.String(), want)
 	}
 }
+
+func TestHttpFailoverProxyRetriesEmptyResult(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	fakeRPC1Server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":null}`))
+	}))
+	defer fakeRPC1Server.Close()
+
+	fakeRPC2Server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"number":"0x10"}}`))
+	}))
+	defer fakeRPC2Server.Close()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Proxy.EmptyResultFailover = EmptyResultFailoverConfig{
+		Methods: []string{"eth_getBlockByNumber"},
+	}
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "Server1",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPC1Server.URL,
+				},
+			},
+		},
+		{
+			Name: "Server2",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPC2Server.URL,
+				},
+			},
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	markHealthy(healthcheckManager)
+	// Server2 knows about block 0x10, so Server1's null is a lagging node.
+	healthcheckManager.healthcheckers[1].(*RPCHealthchecker).blockNumber = 0x10
+
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	for i := 0; i < 8; i++ {
+		requestBody := bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["0x10",false]}`)
+		req, err := http.NewRequest("POST", "/", requestBody)
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":{"number":"0x10"}}`, rr.Body.String())
+	}
+
+	// A block above the cluster head does not exist yet, the null result is
+	// returned as is.
+	healthcheckManager.GetTargetByName("Server2").Taint()
+
+	requestBody := bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["0x10",false]}`)
+	req, err := http.NewRequest("POST", "/", requestBody)
+	assert.Nil(t, err)
+
+	rr := httptest.NewRecorder()
+	httpFailoverProxy.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":null}`, rr.Body.String())
+}
+
+func TestHttpFailoverProxyEmptyResultWithoutRoutableTargets(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	fakeRPC1Server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":null}`))
+	}))
+	defer fakeRPC1Server.Close()
+
+	fakeRPC2Server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"number":"0x10"}}`))
+	}))
+	defer fakeRPC2Server.Close()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Proxy.EmptyResultFailover = EmptyResultFailoverConfig{
+		Methods: []string{"eth_getBlockByNumber"},
+	}
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "Server1",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPC1Server.URL,
+				},
+			},
+		},
+		{
+			Name:   "Shadow",
+			Shadow: true,
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPC2Server.URL,
+				},
+			},
+		},
+		{
+			Name:  "Private",
+			Group: "private",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPC2Server.URL,
+				},
+			},
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	markHealthy(healthcheckManager)
+	healthcheckManager.healthcheckers[0].(*RPCHealthchecker).blockNumber = 0x10
+
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	// Neither the shadow target nor the one of another group may serve the
+	// retry, the null result is returned rather than a 503.
+	requestBody := bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["0x10",false]}`)
+	req, err := http.NewRequest("POST", "/", requestBody)
+	assert.Nil(t, err)
+
+	rr := httptest.NewRecorder()
+	httpFailoverProxy.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "Server1", rr.Header().Get("X-Rpc-Provider"))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":null}`, rr.Body.String())
+}
+
+func TestResponseTimeMethodLabel(t *testing.T) {
+	registerer := prometheus.NewRegistry()
+
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer fakeRPCServer.Close()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Metrics = metrics.NewRegistry(metrics.Config{}, registerer)
+	rpcGatewayConfig.Proxy.Consensus.Methods = []string{"custom_method"}
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "Server1",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPCServer.URL,
+				},
+			},
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+		Metrics: rpcGatewayConfig.Metrics,
+	})
+	markHealthy(healthcheckManager)
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	for _, method := range []string{"eth_chainId", "custom_method", "random_1", "random_2"} {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"`+method+`"}`))
+		assert.Nil(t, err)
+		httpFailoverProxy.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// The methods neither standard nor configured share a label, the
+	// legacy metric keeps the HTTP method.
+	for metric, labels := range map[string][]string{
+		"rpc_gateway_request_duration_seconds": {
+			`{jsonrpc_method="custom_method",target="Server1"} 1`,
+			`{jsonrpc_method="eth_chainId",target="Server1"} 1`,
+			`{jsonrpc_method="other",target="Server1"} 2`,
+		},
+		"zeroex_rpc_gateway_request_duration_seconds": {
+			`{method="POST",provider="Server1"} 4`,
+		},
+	} {
+		count, err := testutil.GatherAndCount(registerer, metric)
+		assert.Nil(t, err)
+		assert.Equal(t, len(labels), count)
+
+		families, err := registerer.Gather()
+		assert.Nil(t, err)
+		for _, family := range families {
+			if family.GetName() != metric {
+				continue
+			}
+			var series []string
+			for _, m := range family.GetMetric() {
+				var pairs []string
+				for _, label := range m.GetLabel() {
+					pairs = append(pairs, label.GetName()+`="`+label.GetValue()+`"`)
+				}
+				series = append(series, "{"+strings.Join(pairs, ",")+"} "+strconv.FormatUint(m.GetHistogram().GetSampleCount(), 10))
+			}
+			assert.ElementsMatch(t, labels, series)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package proxy
 
 import (
 	"net/http"
+	"strings"
+	"time"
 )
 
 type ContextFailoverKeyInt int
@@ -9,8 +11,28 @@ type ContextFailoverKeyInt int
 const (
 	TargetName ContextFailoverKeyInt = iota
 	VisitedTargets
+	DecodedRequest
+	RequestSpan
+	History
 )
 
+// RequestBody is the client request decoded once on arrival and shared
+// between reroutes.
+type RequestBody struct {
+	Raw      []byte
+	Requests []RPCRequest
+	IsBatch  bool
+}
+
+// SingleRequest returns the call when the body is not a batch.
+func (b *RequestBody) SingleRequest() (*RPCRequest, bool) {
+	if b == nil || b.IsBatch || len(b.Requests) != 1 {
+		return nil, false
+	}
+
+	return &b.Requests[0], true
+}
+
 // GetVisitedTargetsFromContext returns the visited targets for request.
 func GetVisitedTargetsFromContext(r *http.Request) []uint {
 	if visitedTargets, ok := r.Context().Value(VisitedTargets).([]uint); ok {
@@ -26,3 +48,71 @@ func GetTargetNameFromContext(r *http.Request) string {
 	}
 	return ""
 }
+
+// GetRequestHistoryFromContext returns the attempts made so far for request.
+func GetRequestHistoryFromContext(r *http.Request) *RequestHistory {
+	if history, ok := r.Context().Value(History).(*RequestHistory); ok {
+		return history
+	}
+	return nil
+}
+
+// GetRequestBodyFromContext returns the decoded JSON-RPC body for request.
+func GetRequestBodyFromContext(r *http.Request) *RequestBody {
+	if body, ok := r.Context().Value(DecodedRequest).(*RequestBody); ok {
+		return body
+	}
+	return nil
+}
+
+// methodLabel is the JSON-RPC method of request as a metric label, "batch"
+// for batches. The methods neither standard nor named in the config are
+// "other", clients can send any string.
+func (h *Proxy) methodLabel(r *http.Request) string {
+	body := GetRequestBodyFromContext(r)
+	if body == nil || len(body.Requests) == 0 {
+		return "unknown"
+	}
+	if body.IsBatch {
+		return "batch"
+	}
+
+	return h.methodName(body.Requests[0].Method)
+}
+
+// methodName is method as a metric label, "other" when it's neither standard
+// nor named in the config.
+func (h *Proxy) methodName(method string) string {
+	if isStandardMethod(method) || h.methods[method] {
+		return method
+	}
+
+	return "other"
+}
+
+// observeResponseTime records the time a target took to serve request. The
+// legacy metric is labelled by the HTTP method, as it always was.
+func (h *Proxy) observeResponseTime(r *http.Request, target string, duration time.Duration) {
+	h.metricResponseTime.ObserveLegacy(duration.Seconds(),
+		[]string{target, h.methodLabel(r)},
+		[]string{target, r.Method})
+}
+
+// isWebsocketHandshake reports whether request asks to upgrade the
+// connection to a websocket. Any other request is plain HTTP, whatever its
+// Upgrade header says.
+func isWebsocketHandshake(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
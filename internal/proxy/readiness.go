@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/0xProject/rpc-gateway/internal/metrics"
+)
+
+const defaultFirstRoundTimeout = time.Minute
+
+// waitsForFirstRound reports whether the gateway is still waiting for the
+// first round of health checks, since started.
+func (c ReadinessConfig) waitsForFirstRound(started time.Time) bool {
+	if c.WaitForFirstRound != nil && !*c.WaitForFirstRound {
+		return false
+	}
+
+	timeout := c.FirstRoundTimeout
+	if timeout == 0 {
+		timeout = defaultFirstRoundTimeout
+	}
+
+	return time.Since(started) < timeout
+}
+
+// Readiness reports whether the gateway can serve traffic: every target
+// completed its first health check round, unless the wait is disabled or
+// timed out, and enough targets are ready, that is enabled, healthy and
+// close enough to the head of their chain. Shadow targets never serve
+// clients and are left out.
+func (h *Proxy) Readiness() metrics.ReadinessReport {
+	config := h.config.HealthChecks.Readiness
+	report := metrics.ReadinessReport{
+		Started:         true,
+		MinReadyTargets: int(max(config.MinReadyTargets, 1)),
+		Targets:         []metrics.TargetReadiness{},
+	}
+
+	for _, target := range h.targets {
+		if target.Config.Shadow {
+			continue
+		}
+		healthChecker := h.healthcheckManager.GetTargetByName(target.Config.Name)
+		if healthChecker == nil {
+			continue
+		}
+
+		state := metrics.TargetReadiness{
+			Name:        target.Config.Name,
+			Checked:     healthChecker.Checked(),
+			Healthy:     healthChecker.IsHealthy(),
+			Tainted:     healthChecker.IsTainted(),
+			Diverged:    healthChecker.IsDiverged(),
+			Disabled:    target.Config.IsDisabled,
+			BlockNumber: healthChecker.BlockNumber(),
+		}
+		if head := h.healthcheckManager.highestBlockNumberOf(h.config.Chain.For(target.Config)); head > state.BlockNumber {
+			state.Lag = head - state.BlockNumber
+		}
+		state.Ready = state.Checked && state.Healthy && !state.Disabled &&
+			(config.MaxBlockLag == 0 || state.Lag <= config.MaxBlockLag)
+
+		if !state.Checked && !state.Disabled {
+			report.Started = false
+		}
+		if state.Ready {
+			report.ReadyTargets++
+		}
+		report.Targets = append(report.Targets, state)
+	}
+
+	switch {
+	case !report.Started && config.waitsForFirstRound(h.startedAt):
+		report.Reason = "first health check round in progress"
+	case report.ReadyTargets < report.MinReadyTargets:
+		report.Reason = fmt.Sprintf("%d ready targets, %d required", report.ReadyTargets, report.MinReadyTargets)
+	default:
+		report.Ready = true
+	}
+
+	return report
+}
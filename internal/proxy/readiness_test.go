@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadiness(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x5f5e0ff"}`))
+	}))
+	defer fakeRPCServer.Close()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.HealthChecks.Timeout = time.Second
+	rpcGatewayConfig.HealthChecks.Readiness = ReadinessConfig{MinReadyTargets: 2, MaxBlockLag: 10}
+	for _, target := range []TargetConfig{{Name: "Primary"}, {Name: "Secondary"}, {Name: "Shadow", Shadow: true}} {
+		target.Connection.HTTP.URL = fakeRPCServer.URL
+		rpcGatewayConfig.Targets = append(rpcGatewayConfig.Targets, target)
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	// Not ready until every target was checked once.
+	report := httpFailoverProxy.Readiness()
+	assert.False(t, report.Ready)
+	assert.False(t, report.Started)
+	assert.Equal(t, "first health check round in progress", report.Reason)
+	assert.Len(t, report.Targets, 2)
+
+	for _, healthChecker := range healthcheckManager.healthcheckers {
+		healthChecker.(*RPCHealthchecker).checkAndSetProbesHealth()
+	}
+	healthcheckManager.healthcheckers[0].(*RPCHealthchecker).blockNumber = 100
+	healthcheckManager.healthcheckers[1].(*RPCHealthchecker).blockNumber = 95
+
+	report = httpFailoverProxy.Readiness()
+	assert.True(t, report.Ready)
+	assert.Equal(t, 2, report.ReadyTargets)
+	assert.Equal(t, uint64(5), report.Targets[1].Lag)
+
+	// A target too far behind the head is not ready.
+	healthcheckManager.healthcheckers[1].(*RPCHealthchecker).blockNumber = 80
+	report = httpFailoverProxy.Readiness()
+	assert.False(t, report.Ready)
+	assert.False(t, report.Targets[1].Ready)
+	assert.Equal(t, "1 ready targets, 2 required", report.Reason)
+}
+
+func TestReadinessFirstRoundWait(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	rpcGatewayConfig := createConfig()
+	for _, name := range []string{"Primary", "Secondary"} {
+		target := TargetConfig{Name: name}
+		target.Connection.HTTP.URL = "http://127.0.0.1:0"
+		rpcGatewayConfig.Targets = append(rpcGatewayConfig.Targets, target)
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	primary := healthcheckManager.healthcheckers[0].(*RPCHealthchecker)
+	primary.checked = true
+	primary.isHealthy = true
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	report := httpFailoverProxy.Readiness()
+	assert.False(t, report.Ready)
+	assert.Equal(t, "first health check round in progress", report.Reason)
+
+	// Once the wait timed out, the targets not checked yet are not waited
+	// for.
+	httpFailoverProxy.startedAt = time.Now().Add(-2 * time.Minute)
+	report = httpFailoverProxy.Readiness()
+	assert.True(t, report.Ready)
+	assert.False(t, report.Started)
+	assert.Equal(t, 1, report.ReadyTargets)
+
+	// Nor when the wait is disabled.
+	wait := false
+	httpFailoverProxy.startedAt = time.Now()
+	httpFailoverProxy.config.HealthChecks.Readiness.WaitForFirstRound = &wait
+	assert.True(t, httpFailoverProxy.Readiness().Ready)
+}
@@ -30,7 +30,7 @@ func doProcessRequest(r *http.Request, config TargetConfig) error {
 	var buf bytes.Buffer
 	var err error
 
-	if r.Header.Get("Upgrade") != "" {
+	if isWebsocketHandshake(r) {
 		return nil
 	}
 
@@ -152,7 +152,7 @@ func NewReverseProxy(targetConfig TargetConfig, config Config) (*httputil.Revers
 	}
 
 	var wsProxy *httputil.ReverseProxy
-	if config.Solana {
+	if config.Chain.chainFamilyOf(targetConfig).WebsocketOnNextPort() {
 		wsUrl := targetConfig.Connection.WS.URL
 		if wsUrl == "" {
 			wsUrl = targetConfig.Connection.HTTP.URL
@@ -187,6 +187,8 @@ func NewReverseProxy(targetConfig TargetConfig, config Config) (*httputil.Revers
 			if err := doProcessRequest(r, targetConfig); err != nil {
 				zap.L().Error("cannot process request", zap.Error(err))
 			}
+			injectTraceContext(r)
+			forwardRequestID(r.Context(), r.Header)
 
 			zap.L().Debug("request forward", zap.String("WS", r.URL.String()))
 		}
@@ -206,6 +208,8 @@ func NewReverseProxy(targetConfig TargetConfig, config Config) (*httputil.Revers
 		if err := doProcessRequest(r, targetConfig); err != nil {
 			zap.L().Error("cannot process request", zap.Error(err))
 		}
+		injectTraceContext(r)
+		forwardRequestID(r.Context(), r.Header)
 
 		zap.L().Debug("request forward", zap.String("URL", r.URL.String()))
 	}
@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"net/http"
+	"slices"
+)
+
+const (
+	// Targets without a group serve all the regular traffic.
+	defaultTargetGroup = ""
+
+	txModePrivate = "private"
+	txModePublic  = "public"
+
+	defaultTxModeHeader = "X-Rpc-Tx-Mode"
+)
+
+func defaultPrivateMethods() []string {
+	return []string{"eth_sendRawTransaction", "eth_sendPrivateTransaction"}
+}
+
+// targetGroups returns the target groups request can be routed to, in order
+// of preference.
+func (h *Proxy) targetGroups(r *http.Request) []string {
+	config := h.config.Proxy.PrivateTransactions
+	if !h.isPrivateSubmission(r) {
+		return []string{defaultTargetGroup}
+	}
+
+	if config.Fallback == txModePublic {
+		return []string{config.Group, defaultTargetGroup}
+	}
+
+	return []string{config.Group}
+}
+
+// isPrivateSubmission reports whether request is a transaction submission
+// that should go to the private targets. Clients can choose the mode per
+// request with the mode header.
+func (h *Proxy) isPrivateSubmission(r *http.Request) bool {
+	config := h.config.Proxy.PrivateTransactions
+	if config.Group == "" {
+		return false
+	}
+
+	body := GetRequestBodyFromContext(r)
+	if body == nil || len(body.Requests) == 0 {
+		return false
+	}
+
+	methods := config.Methods
+	if len(methods) == 0 {
+		methods = defaultPrivateMethods()
+	}
+	for _, request := range body.Requests {
+		if !slices.Contains(methods, request.Method) {
+			return false
+		}
+	}
+
+	header := config.ModeHeader
+	if header == "" {
+		header = defaultTxModeHeader
+	}
+
+	mode := config.Mode
+	if requested := r.Header.Get(header); requested == txModePrivate || requested == txModePublic {
+		mode = requested
+	}
+
+	return mode != txModePublic
+}
+
+// groupFilter accepts the targets of group only.
+func (h *Proxy) groupFilter(group string) TargetFilter {
+	return func(index int, _ Healthchecker) bool {
+		return h.targets[index].Config.Group == group && !h.targets[index].Config.Shadow
+	}
+}
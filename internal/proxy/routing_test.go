@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrivateTransactionRouting(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Proxy.PrivateTransactions = PrivateTransactionsConfig{
+		Group: "private",
+	}
+	for name, group := range map[string]string{"Public": "", "Private": "private"} {
+		name := name
+		fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		}))
+		defer fakeRPCServer.Close()
+
+		rpcGatewayConfig.Targets = append(rpcGatewayConfig.Targets, TargetConfig{
+			Name: name,
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPCServer.URL,
+				},
+			},
+			Group: group,
+		})
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	serve := func(method, mode string) *httptest.ResponseRecorder {
+		requestBody := bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"` + method + `","params":["0x01"]}`)
+		req, err := http.NewRequest("POST", "/", requestBody)
+		assert.Nil(t, err)
+		if mode != "" {
+			req.Header.Set("X-Rpc-Tx-Mode", mode)
+		}
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	for i := 0; i < 8; i++ {
+		assert.Equal(t, "Public", serve("eth_blockNumber", "").Body.String())
+		assert.Equal(t, "Private", serve("eth_sendRawTransaction", "").Body.String())
+		assert.Equal(t, "Public", serve("eth_sendRawTransaction", "public").Body.String())
+	}
+
+	// No fallback to the public targets unless configured.
+	healthcheckManager.TaintTarget("Private")
+	assert.Equal(t, http.StatusServiceUnavailable, serve("eth_sendRawTransaction", "").Code)
+
+	httpFailoverProxy.config.Proxy.PrivateTransactions.Fallback = "public"
+	assert.Equal(t, "Public", serve("eth_sendRawTransaction", "").Body.String())
+}
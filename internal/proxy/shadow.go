@@ -0,0 +1,214 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultShadowSampleRate  = 0.1
+	defaultShadowTimeout     = 30 * time.Second
+	defaultShadowMaxBodySize = 1 << 20
+)
+
+func defaultShadowMethods() []string {
+	return []string{
+		"eth_blockNumber",
+		"eth_call",
+		"eth_chainId",
+		"eth_estimateGas",
+		"eth_feeHistory",
+		"eth_gasPrice",
+		"eth_getBalance",
+		"eth_getBlockByHash",
+		"eth_getBlockByNumber",
+		"eth_getBlockReceipts",
+		"eth_getCode",
+		"eth_getLogs",
+		"eth_getProof",
+		"eth_getStorageAt",
+		"eth_getTransactionByHash",
+		"eth_getTransactionCount",
+		"eth_getTransactionReceipt",
+		"eth_maxPriorityFeePerGas",
+		"net_version",
+	}
+}
+
+// mirrors reports whether every call of a request may be sent to the shadow
+// targets.
+func (c ShadowConfig) mirrors(requests []RPCRequest) bool {
+	methods := c.Methods
+	if len(methods) == 0 {
+		methods = defaultShadowMethods()
+	}
+
+	for _, request := range requests {
+		if !slices.ContainsFunc(methods, func(pattern string) bool { return matchMethod(pattern, request.Method) }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c ShadowConfig) sampleRate() float64 {
+	if c.SampleRate <= 0 {
+		return defaultShadowSampleRate
+	}
+
+	return c.SampleRate
+}
+
+func (c ShadowConfig) timeout() time.Duration {
+	if c.Timeout == 0 {
+		return defaultShadowTimeout
+	}
+
+	return c.Timeout
+}
+
+func (c ShadowConfig) maxBodySize() int {
+	if c.MaxBodySize <= 0 {
+		return defaultShadowMaxBodySize
+	}
+
+	return c.MaxBodySize
+}
+
+// shadowFilter rejects the shadow targets, which never serve clients.
+func (h *Proxy) shadowFilter() TargetFilter {
+	return func(index int, _ Healthchecker) bool {
+		return !h.targets[index].Config.Shadow
+	}
+}
+
+// shadowTargets returns the healthy shadow targets a sampled request is
+// mirrored to, the ones of the group it's routed to. Only the reads served by
+// a single target are mirrored.
+func (h *Proxy) shadowTargets(r *http.Request) []*HTTPTarget {
+	body := GetRequestBodyFromContext(r)
+	if body == nil || len(body.Requests) == 0 || isWebsocketHandshake(r) {
+		return nil
+	}
+	if !h.config.Proxy.Shadow.mirrors(body.Requests) {
+		return nil
+	}
+	if request, ok := body.SingleRequest(); ok && (h.isBroadcast(request) || h.isConsensus(r, request)) {
+		return nil
+	}
+
+	group := h.targetGroups(r)[0]
+	var targets []*HTTPTarget
+	for _, target := range h.targets {
+		if !target.Config.Shadow || target.Config.IsDisabled || target.Config.Group != group {
+			continue
+		}
+		if h.healthcheckManager.IsTargetHealthy(target.Config.Name) {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	if rate := h.config.Proxy.Shadow.sampleRate(); rate < 1 && rand.Float64() >= rate { // nolint:gosec
+		return nil
+	}
+
+	return targets
+}
+
+// mirror sends the request served to the client to the shadow targets in
+// the background, and compares their responses and latency with the ones of
+// the primary. The responses of the shadow targets are dropped.
+func (h *Proxy) mirror(r *http.Request, history *RequestHistory, w *responseRecorder, targets []*HTTPTarget) {
+	if w.status != http.StatusOK || w.body.Len() == 0 || w.body.Len() >= h.config.Proxy.Shadow.maxBodySize() {
+		return
+	}
+
+	primary := "gateway"
+	primaryLatency := time.Since(history.Start)
+	if attempt := history.last(); attempt != nil && attempt.Error == "" {
+		primary = attempt.Target
+		primaryLatency = attempt.elapsed
+	}
+	expected, err := normalizeBody(w.body.Bytes())
+	if err != nil {
+		return
+	}
+	body := GetRequestBodyFromContext(r).Raw
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), h.config.Proxy.Shadow.timeout())
+	var wg sync.WaitGroup
+	wg.Add(len(targets))
+	for _, target := range targets {
+		go func(target *HTTPTarget) {
+			defer wg.Done()
+			start := time.Now()
+			data, err := target.Call(ctx, body)
+			latency := time.Since(start)
+
+			outcome := "match"
+			if err != nil {
+				outcome = "failed"
+			} else if actual, err := normalizeBody(data); err != nil || actual != expected {
+				outcome = "mismatch"
+				zap.L().Info("shadow response mismatch",
+					zap.String("requestId", history.ID),
+					zap.String("shadow", target.Config.Name),
+					zap.String("primary", primary),
+					zap.String("methods", h.methodLabel(r)))
+			}
+
+			h.metricShadowRequests.Inc(target.Config.Name, outcome)
+			if err == nil {
+				h.metricShadowLatencyDiff.Observe((latency - primaryLatency).Seconds(), target.Config.Name)
+			}
+		}(target)
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+	}()
+}
+
+// normalizeBody normalizes a response body, the responses of a batch by
+// request id since the targets may answer them in any order.
+func normalizeBody(body []byte) (string, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		response, err := ParseRPCResponse(body)
+		if err != nil {
+			return "", err
+		}
+
+		return normalizeResponse(response)
+	}
+
+	var responses []*RPCResponse
+	if err := json.Unmarshal(body, &responses); err != nil {
+		return "", err
+	}
+	keys := make([]string, 0, len(responses))
+	for _, response := range responses {
+		key, err := normalizeResponse(response)
+		if err != nil {
+			return "", err
+		}
+		keys = append(keys, string(response.ID)+"="+key)
+	}
+	sort.Strings(keys)
+
+	return strings.Join(keys, "\n"), nil
+}
@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShadowTraffic(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	prometheus.DefaultRegisterer = registry
+
+	var shadowResponse atomic.Value
+	shadowResponse.Store(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`)
+	var shadowReceived atomic.Int32
+	primaryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+	}))
+	defer primaryServer.Close()
+	shadowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		shadowReceived.Add(1)
+		w.Write([]byte(shadowResponse.Load().(string)))
+	}))
+	defer shadowServer.Close()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Proxy.Shadow = ShadowConfig{SampleRate: 1}
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "Primary",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: primaryServer.URL,
+				},
+			},
+		},
+		{
+			Name:   "Shadow",
+			Shadow: true,
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: shadowServer.URL,
+				},
+			},
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	markHealthy(healthcheckManager)
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	// The shadow target is not a fallback, even when healthy.
+	assert.Nil(t, httpFailoverProxy.GetNextTargetExcluding([]uint{0}))
+
+	serve := func() {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`))
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+
+		// The shadow target never serves the client.
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "Primary", rr.Header().Get("X-Rpc-Provider"))
+	}
+
+	serve()
+	assert.Eventually(t, func() bool { return shadowReceived.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	shadowResponse.Store(`{"jsonrpc":"2.0","id":1,"result":"0xf"}`)
+	serve()
+	assert.Eventually(t, func() bool {
+		count, err := testutil.GatherAndCount(registry, "rpc_gateway_shadow_latency_diff_seconds")
+		return err == nil && count == 1 && shadowReceived.Load() == 2
+	}, time.Second, 10*time.Millisecond)
+
+	expected := `
+# HELP rpc_gateway_shadow_requests_total Total number of requests mirrored to a given shadow target by outcome
+# TYPE rpc_gateway_shadow_requests_total counter
+rpc_gateway_shadow_requests_total{outcome="match",target="Shadow"} 1
+rpc_gateway_shadow_requests_total{outcome="mismatch",target="Shadow"} 1
+`
+	assert.Eventually(t, func() bool {
+		return testutil.GatherAndCompare(registry, strings.NewReader(expected), "rpc_gateway_shadow_requests_total") == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestNormalizeBatchBody(t *testing.T) {
+	a, err := normalizeBody([]byte(`[{"jsonrpc":"2.0","id":1,"result":"0x1"},{"jsonrpc":"2.0","id":2,"result":"0xA"}]`))
+	assert.Nil(t, err)
+	b, err := normalizeBody([]byte(`[{"jsonrpc":"2.0","id":2,"result":"0xa"},{"jsonrpc":"2.0","id":1,"result":"0x1"}]`))
+	assert.Nil(t, err)
+	assert.Equal(t, a, b)
+}
+
+func TestShadowMethods(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	var shadowReceived atomic.Int32
+	primaryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+	}))
+	defer primaryServer.Close()
+	shadowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		shadowReceived.Add(1)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+	}))
+	defer shadowServer.Close()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Proxy.Shadow = ShadowConfig{SampleRate: 1}
+	rpcGatewayConfig.Proxy.Consensus = ConsensusConfig{Enabled: true, Methods: []string{"eth_getBalance"}, Targets: 1}
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{Name: "Primary", Connection: TargetConfigConnection{HTTP: TargetConnectionHTTP{URL: primaryServer.URL}}},
+		{Name: "Shadow", Shadow: true, Connection: TargetConfigConnection{HTTP: TargetConnectionHTTP{URL: shadowServer.URL}}},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	markHealthy(healthcheckManager)
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	serve := func(body string) {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(body))
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	// Submissions, filters and the calls served in consensus mode are not
+	// mirrored, nor are batches with any of them.
+	serve(`{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":["0x01"]}`)
+	serve(`{"jsonrpc":"2.0","id":1,"method":"eth_newFilter","params":[{}]}`)
+	serve(`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000000000","latest"]}`)
+	serve(`[{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"},{"jsonrpc":"2.0","id":2,"method":"eth_uninstallFilter","params":["0x1"]}]`)
+
+	// The reads are.
+	serve(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`)
+	assert.Eventually(t, func() bool { return shadowReceived.Load() == 1 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), shadowReceived.Load())
+}
+
+func TestShadowConfigMirrors(t *testing.T) {
+	assert.True(t, ShadowConfig{}.mirrors([]RPCRequest{{Method: "eth_call"}, {Method: "eth_getLogs"}}))
+	assert.False(t, ShadowConfig{}.mirrors([]RPCRequest{{Method: "eth_call"}, {Method: "eth_sendRawTransaction"}}))
+	assert.False(t, ShadowConfig{}.mirrors([]RPCRequest{{Method: "eth_getFilterChanges"}}))
+
+	config := ShadowConfig{Methods: []string{"trace_*"}}
+	assert.True(t, config.mirrors([]RPCRequest{{Method: "trace_block"}}))
+	assert.False(t, config.mirrors([]RPCRequest{{Method: "eth_call"}}))
+}
@@ -0,0 +1,170 @@
+package proxy
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/0xProject/rpc-gateway/internal/metrics"
+)
+
+const (
+	defaultSLOWindow = 5 * time.Minute
+	// Bounds the memory used by a busy target, the oldest samples are
+	// dropped first.
+	maxSLOSamples = 10000
+
+	sloSourceCheck   = "healthcheck"
+	sloSourceTraffic = "traffic"
+)
+
+// TargetSLO summarizes the samples of a target over the rolling window.
+// Latencies are of the successful samples, from health checks and live
+// traffic. ErrorRate is the fraction of the proxied requests that failed,
+// Availability the fraction of the health checks that succeeded.
+type TargetSLO struct {
+	Target       string  `json:"target"`
+	Window       string  `json:"window"`
+	Requests     int     `json:"requests"`
+	Checks       int     `json:"checks"`
+	P50Ms        float64 `json:"p50Ms"`
+	P95Ms        float64 `json:"p95Ms"`
+	P99Ms        float64 `json:"p99Ms"`
+	ErrorRate    float64 `json:"errorRate"`
+	Availability float64 `json:"availability"`
+}
+
+// sloSample is the outcome of a health check or of a proxied request.
+type sloSample struct {
+	at      time.Time
+	latency time.Duration
+	failed  bool
+	check   bool
+}
+
+// sloWindow keeps the samples of a target over the last length.
+type sloWindow struct {
+	name    string
+	length  time.Duration
+	summary *metrics.SummaryVec
+
+	mu      sync.Mutex
+	samples []sloSample
+}
+
+func newSLOWindow(name string, config SLOConfig, summary *metrics.SummaryVec) *sloWindow {
+	return &sloWindow{
+		name:    name,
+		length:  sloWindowOrDefault(config),
+		summary: summary,
+	}
+}
+
+func sloWindowOrDefault(config SLOConfig) time.Duration {
+	if config.Window == 0 {
+		return defaultSLOWindow
+	}
+
+	return config.Window
+}
+
+func (w *sloWindow) observeCheck(latency time.Duration, err error) {
+	w.observe(sloSample{at: time.Now(), latency: latency, failed: err != nil, check: true})
+}
+
+func (w *sloWindow) observeRequest(latency time.Duration, failed bool) {
+	w.observe(sloSample{at: time.Now(), latency: latency, failed: failed})
+}
+
+func (w *sloWindow) observe(sample sloSample) {
+	if w == nil {
+		return
+	}
+
+	if w.summary != nil && !sample.failed {
+		source := sloSourceTraffic
+		if sample.check {
+			source = sloSourceCheck
+		}
+		w.summary.Observe(sample.latency.Seconds(), w.name, source)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.samples = append(w.samples, sample)
+	w.prune(sample.at)
+}
+
+// prune drops the samples that left the window. Samples are appended in
+// time order, so the expired ones are at the front.
+func (w *sloWindow) prune(now time.Time) {
+	expired := 0
+	for expired < len(w.samples) && now.Sub(w.samples[expired].at) > w.length {
+		expired++
+	}
+	if overflow := len(w.samples) - expired - maxSLOSamples; overflow > 0 {
+		expired += overflow
+	}
+	if expired > 0 {
+		w.samples = slices.Delete(w.samples, 0, expired)
+	}
+}
+
+func (w *sloWindow) report() TargetSLO {
+	w.mu.Lock()
+	w.prune(time.Now())
+	samples := slices.Clone(w.samples)
+	w.mu.Unlock()
+
+	slo := TargetSLO{
+		Target: w.name,
+		Window: w.length.String(),
+	}
+
+	var latencies []time.Duration
+	var failedRequests, passedChecks int
+	for _, sample := range samples {
+		if sample.check {
+			slo.Checks++
+			if !sample.failed {
+				passedChecks++
+			}
+		} else {
+			slo.Requests++
+			if sample.failed {
+				failedRequests++
+			}
+		}
+		if !sample.failed {
+			latencies = append(latencies, sample.latency)
+		}
+	}
+
+	if slo.Requests > 0 {
+		slo.ErrorRate = float64(failedRequests) / float64(slo.Requests)
+	}
+	if slo.Checks > 0 {
+		slo.Availability = float64(passedChecks) / float64(slo.Checks)
+	}
+
+	slices.Sort(latencies)
+	slo.P50Ms = percentileMs(latencies, 0.5)
+	slo.P95Ms = percentileMs(latencies, 0.95)
+	slo.P99Ms = percentileMs(latencies, 0.99)
+
+	return slo
+}
+
+// percentileMs returns the nearest-rank percentile of the sorted latencies,
+// in milliseconds.
+func percentileMs(sorted []time.Duration, percentile float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(percentile*float64(len(sorted)))) - 1
+	rank = max(0, min(rank, len(sorted)-1))
+
+	return float64(sorted[rank].Microseconds()) / 1000
+}
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSLOWindow(t *testing.T) {
+	window := newSLOWindow("Server1", SLOConfig{Window: time.Minute}, nil)
+
+	// An expired sample is not reported.
+	window.samples = append(window.samples, sloSample{at: time.Now().Add(-2 * time.Minute), latency: time.Hour})
+
+	for i := 1; i <= 100; i++ {
+		window.observeRequest(time.Duration(i)*time.Millisecond, false)
+	}
+	window.observeRequest(time.Second, true)
+	window.observeCheck(time.Millisecond, nil)
+	window.observeCheck(time.Millisecond, errors.New("timeout"))
+
+	slo := window.report()
+	assert.Equal(t, "Server1", slo.Target)
+	assert.Equal(t, "1m0s", slo.Window)
+	assert.Equal(t, 101, slo.Requests)
+	assert.Equal(t, 2, slo.Checks)
+	assert.Equal(t, 50.0, slo.P50Ms)
+	assert.Equal(t, 95.0, slo.P95Ms)
+	assert.Equal(t, 99.0, slo.P99Ms)
+	assert.InDelta(t, 1.0/101, slo.ErrorRate, 1e-9)
+	assert.Equal(t, 0.5, slo.Availability)
+}
+
+func TestSLORecordsTraffic(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	rateLimitedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer rateLimitedServer.Close()
+	healthyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer healthyServer.Close()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Targets = []TargetConfig{
+		{
+			Name: "RateLimited",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: rateLimitedServer.URL,
+				},
+			},
+		},
+		{
+			Name: "Healthy",
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: healthyServer.URL,
+				},
+			},
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	markHealthy(healthcheckManager)
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	serve := func(status int) {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`))
+		assert.Nil(t, err)
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+		assert.Equal(t, status, rr.Code)
+	}
+
+	// Only the rate limited target is available at first.
+	healthcheckManager.TaintTarget("Healthy")
+	serve(http.StatusServiceUnavailable)
+
+	healthcheckManager.GetTargetByName("Healthy").RemoveTaint()
+	healthcheckManager.TaintTarget("RateLimited")
+	for i := 0; i < 8; i++ {
+		serve(http.StatusOK)
+	}
+
+	healthy := healthcheckManager.GetSLO("Healthy")
+	assert.Equal(t, 8, healthy.Requests)
+	assert.Equal(t, 0.0, healthy.ErrorRate)
+	assert.Greater(t, healthy.P99Ms, 0.0)
+
+	rateLimited := healthcheckManager.GetSLO("RateLimited")
+	assert.Equal(t, 1, rateLimited.Requests)
+	assert.Equal(t, 1.0, rateLimited.ErrorRate)
+	assert.Equal(t, 0.0, rateLimited.P50Ms)
+
+	assert.Nil(t, healthcheckManager.GetSLO("Unknown"))
+	assert.Len(t, healthcheckManager.GetSLOs(), 2)
+}
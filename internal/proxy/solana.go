@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// About a minute of slots.
+	defaultMaxSlotLag      = 150
+	defaultMaxBlockhashAge = time.Minute
+)
+
+// solanaFamily serves Solana, whose nodes listen for websockets on the port
+// next to the HTTP one (8899 and 8900 by default).
+type solanaFamily struct{}
+
+func (solanaFamily) HeadHeight(ctx context.Context, h *RPCHealthchecker) (uint64, error) {
+	return h.checkSolanaSlotNumber(ctx)
+}
+
+// LivenessChecks is the health profile of Solana nodes:
+// - `getHealth` - the node reports itself healthy
+// - slot lag - the slot is not too far behind the cluster head
+// - `getLatestBlockhash` - the latest blockhash keeps changing
+// - `getVersion` - the node reports its version
+func (solanaFamily) LivenessChecks(h *RPCHealthchecker) []probeCheck {
+	return []probeCheck{
+		{name: "getHealth", weight: 1, run: h.checkSolanaHealth},
+		{name: "slotLag", weight: 1, run: h.checkSolanaSlotLag},
+		{name: "getLatestBlockhash", weight: 1, run: h.checkSolanaBlockhash},
+		{name: "getVersion", weight: 1, run: h.checkSolanaVersion},
+	}
+}
+
+// Exceptions are the errors of the nodes lagging behind the cluster.
+func (solanaFamily) Exceptions() []Exception {
+	return []Exception{
+		{Match: "block height exceeded", Message: "Solana: block height exceeded"},
+		{Match: "Blockhash not found", Message: "Solana: Blockhash not found"},
+		{Match: "Block not available for slot", Message: "Solana: Block not available for slot"},
+	}
+}
+
+func (solanaFamily) WebsocketOnNextPort() bool {
+	return true
+}
+
+// checkSolanaHealth fails when the node is not ok, a node behind the
+// cluster answers with an error such as "Node is behind by 42 slots".
+func (h *RPCHealthchecker) checkSolanaHealth() error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	var health string
+	if err := h.client.CallContext(ctx, &health, "getHealth"); err != nil {
+		return err
+	}
+	if health != "ok" {
+		return fmt.Errorf("node reports %q", health)
+	}
+
+	return nil
+}
+
+func (h *RPCHealthchecker) checkSolanaSlotLag() error {
+	maxLag := h.config.SolanaHealth.MaxSlotLag
+	if maxLag == 0 {
+		maxLag = defaultMaxSlotLag
+	}
+
+	return h.checkHeadLag(maxLag)
+}
+
+// checkSolanaBlockhash fails when the latest blockhash has not changed for
+// longer than the max age. Blockhashes change with every block, a node
+// serving a stale one cannot land transactions.
+func (h *RPCHealthchecker) checkSolanaBlockhash() error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	var result struct {
+		Value struct {
+			Blockhash string `json:"blockhash"`
+		} `json:"value"`
+	}
+	if err := h.client.CallContext(ctx, &result, "getLatestBlockhash"); err != nil {
+		return err
+	}
+	if result.Value.Blockhash == "" {
+		return errors.New("empty blockhash")
+	}
+
+	maxAge := h.config.SolanaHealth.MaxBlockhashAge
+	if maxAge == 0 {
+		maxAge = defaultMaxBlockhashAge
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if result.Value.Blockhash != h.blockhash {
+		h.blockhash = result.Value.Blockhash
+		h.blockhashChangedAt = time.Now()
+		return nil
+	}
+	if age := time.Since(h.blockhashChangedAt); age > maxAge {
+		return fmt.Errorf("blockhash %s unchanged for %s", h.blockhash, age.Round(time.Second))
+	}
+
+	return nil
+}
+
+func (h *RPCHealthchecker) checkSolanaVersion() error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	var result struct {
+		SolanaCore string `json:"solana-core"`
+	}
+	if err := h.client.CallContext(ctx, &result, "getVersion"); err != nil {
+		return err
+	}
+	if result.SolanaCore == "" {
+		return errors.New("no solana-core version")
+	}
+	zap.L().Debug("fetched version", zap.String("version", result.SolanaCore), zap.String("rpcProvider", h.config.Name))
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSolanaHealthProfile(t *testing.T) {
+	health := `"ok"`
+	blockhash := "EkSnNWid2cvwEVnVx9aBqawnmiCNiDgp3gUdkDPTKN1N"
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request RPCRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		result := ""
+		switch request.Method {
+		case "getHealth":
+			if health != `"ok"` {
+				w.Write(NewRPCError(request.ID, -32005, "Node is behind by 42 slots"))
+				return
+			}
+			result = health
+		case "getLatestBlockhash":
+			result = `{"context":{"slot":2792},"value":{"blockhash":"` + blockhash + `","lastValidBlockHeight":3090}}`
+		case "getVersion":
+			result = `{"solana-core":"1.17.16","feature-set":4215500110}`
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(request.ID) + `,"result":` + result + `}`))
+	}))
+	defer fakeRPCServer.Close()
+
+	head := uint64(1000)
+	healthchecker, err := NewHealthchecker(RPCHealthcheckerConfig{
+		URL:              fakeRPCServer.URL,
+		Name:             "Solana",
+		Chain:            ChainSolana,
+		Timeout:          time.Second,
+		FailureThreshold: 2,
+		SuccessThreshold: 1,
+		SolanaHealth: SolanaHealthConfig{
+			MaxSlotLag:      100,
+			MaxBlockhashAge: time.Minute,
+		},
+		ClusterHead: func() uint64 { return head },
+	})
+	assert.Nil(t, err)
+	rpcHealthchecker := healthchecker.(*RPCHealthchecker)
+	rpcHealthchecker.blockNumber = 950
+
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.True(t, healthchecker.IsHealthy())
+
+	// A node behind the cluster head fails once the threshold is reached.
+	head = 1200
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.True(t, healthchecker.IsHealthy())
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.False(t, healthchecker.IsHealthy())
+
+	head = 1000
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.True(t, healthchecker.IsHealthy())
+
+	// So does a node reporting itself unhealthy.
+	health = `"behind"`
+	rpcHealthchecker.checkAndSetProbesHealth()
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.False(t, healthchecker.IsHealthy())
+	health = `"ok"`
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.True(t, healthchecker.IsHealthy())
+
+	// And a node stuck on the same blockhash.
+	rpcHealthchecker.blockhashChangedAt = time.Now().Add(-2 * time.Minute)
+	assert.NotNil(t, rpcHealthchecker.checkSolanaBlockhash())
+	blockhash = "4sGjMW1sUnHzSxGspuhpqLDx6wiyjNtZAMdL4VZHirAn"
+	assert.Nil(t, rpcHealthchecker.checkSolanaBlockhash())
+}
@@ -0,0 +1,167 @@
+package proxy
+
+import (
+	"hash/fnv"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	stickyKeyIP     = "ip"
+	stickyKeyAPIKey = "apiKey"
+	stickyKeyHeader = "header"
+
+	apiKeyHeader     = "X-Api-Key"
+	apiKeyQueryParam = "apiKey"
+
+	defaultStickySessionTTL = 10 * time.Minute
+)
+
+// StickySession is a client currently assigned to a target.
+type StickySession struct {
+	Client   string    `json:"client"`
+	Target   string    `json:"target"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+type stickySessions struct {
+	ttl       time.Duration
+	sessions  map[string]StickySession
+	lastPrune time.Time
+	mu        sync.Mutex
+}
+
+func newStickySessions(config StickinessConfig) *stickySessions {
+	ttl := config.TTL
+	if ttl == 0 {
+		ttl = defaultStickySessionTTL
+	}
+
+	return &stickySessions{
+		ttl:       ttl,
+		sessions:  map[string]StickySession{},
+		lastPrune: time.Now(),
+	}
+}
+
+func (s *stickySessions) record(client, target string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sessions[client] = StickySession{
+		Client:   client,
+		Target:   target,
+		LastSeen: now,
+	}
+
+	if now.Sub(s.lastPrune) > s.ttl {
+		s.prune(now)
+	}
+}
+
+func (s *stickySessions) prune(now time.Time) {
+	for client, session := range s.sessions {
+		if now.Sub(session.LastSeen) > s.ttl {
+			delete(s.sessions, client)
+		}
+	}
+	s.lastPrune = now
+}
+
+func (s *stickySessions) list() []StickySession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(time.Now())
+
+	sessions := make([]StickySession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Client < sessions[j].Client
+	})
+
+	return sessions
+}
+
+// stickyKey identifies the client of request, or returns an empty string
+// when stickiness is disabled or the key is missing.
+func (h *Proxy) stickyKey(r *http.Request) string {
+	config := h.config.Proxy.Stickiness
+
+	switch config.Key {
+	case stickyKeyIP:
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+		return host
+	case stickyKeyAPIKey:
+		if key := r.Header.Get(apiKeyHeader); key != "" {
+			return maskAPIKey(key)
+		}
+		return maskAPIKey(r.URL.Query().Get(apiKeyQueryParam))
+	case stickyKeyHeader:
+		return r.Header.Get(config.Header)
+	}
+
+	return ""
+}
+
+// maskAPIKey keeps API keys out of the admin API while still giving a
+// stable client identifier.
+func maskAPIKey(key string) string {
+	if key == "" {
+		return ""
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(key)) // nolint:errcheck
+
+	return "apikey:" + strconv.FormatUint(hash.Sum64(), 16)
+}
+
+// stickyTargetIndex maps the client to a target with rendezvous hashing:
+// targets are ranked by the hash of the client key and the target name, and
+// the best ranked available target wins. Adding or removing a target only
+// moves the clients assigned to it, and a client returns to its target once
+// it's healthy again.
+func (h *Proxy) stickyTargetIndex(key string, excludedIdx []uint, filter TargetFilter) int {
+	best := -1
+	var bestScore uint64
+
+	for index, target := range h.targets {
+		if slices.Contains(excludedIdx, uint(index)) {
+			continue
+		}
+
+		healthchecker := h.healthcheckManager.GetTargetByName(target.Config.Name)
+		if healthchecker == nil || !healthchecker.IsHealthy() {
+			continue
+		}
+		if filter != nil && !filter(index, healthchecker) {
+			continue
+		}
+
+		hash := fnv.New64a()
+		hash.Write([]byte(key + "/" + target.Config.Name)) // nolint:errcheck
+		if score := hash.Sum64(); best < 0 || score > bestScore {
+			best = index
+			bestScore = score
+		}
+	}
+
+	return best
+}
+
+// GetStickySessions returns the current client to target assignments.
+func (h *Proxy) GetStickySessions() []StickySession {
+	return h.sessions.list()
+}
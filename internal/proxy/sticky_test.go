@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStickySessions(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	rpcGatewayConfig := createConfig()
+	rpcGatewayConfig.Proxy.Stickiness = StickinessConfig{
+		Key:    "header",
+		Header: "X-Session-Id",
+	}
+	for _, name := range []string{"Server1", "Server2", "Server3"} {
+		name := name
+		fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		}))
+		defer fakeRPCServer.Close()
+
+		rpcGatewayConfig.Targets = append(rpcGatewayConfig.Targets, TargetConfig{
+			Name: name,
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPCServer.URL,
+				},
+			},
+		})
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+	markHealthy(healthcheckManager)
+
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	serve := func() string {
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`))
+		assert.Nil(t, err)
+		req.Header.Set("X-Session-Id", "indexer-1")
+
+		rr := httptest.NewRecorder()
+		httpFailoverProxy.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		return rr.Body.String()
+	}
+
+	assigned := serve()
+	for i := 0; i < 16; i++ {
+		assert.Equal(t, assigned, serve())
+	}
+	sessions := httpFailoverProxy.GetStickySessions()
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, "indexer-1", sessions[0].Client)
+	assert.Equal(t, assigned, sessions[0].Target)
+
+	// The client falls back to a different target only while its own is
+	// unhealthy.
+	healthcheckManager.TaintTarget(assigned)
+	fallback := serve()
+	assert.NotEqual(t, assigned, fallback)
+	for i := 0; i < 16; i++ {
+		assert.Equal(t, fallback, serve())
+	}
+
+	healthcheckManager.GetTargetByName(assigned).RemoveTaint()
+	assert.Equal(t, assigned, serve())
+}
@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// About a minute of checkpoints.
+	defaultMaxCheckpointLag = 240
+	defaultMaxCheckpointAge = time.Minute
+)
+
+// suiFamily serves Sui, whose head is the latest checkpoint executed by the
+// node.
+type suiFamily struct{}
+
+func (suiFamily) HeadHeight(ctx context.Context, h *RPCHealthchecker) (uint64, error) {
+	start := time.Now()
+	checkpoint, err := h.checkSuiCheckpointNumber(ctx)
+	if err != nil {
+		zap.L().Warn("error fetching the checkpoint number", zap.Error(err), zap.String("name", h.config.Name))
+		return 0, err
+	}
+	h.observeHead("sui_getLatestCheckpointSequenceNumber", start, checkpoint)
+	zap.L().Debug("fetched checkpoint", zap.Uint64("checkpointNumber", checkpoint), zap.String("rpcProvider", h.config.Name))
+
+	return checkpoint, nil
+}
+
+// LivenessChecks is the health profile of Sui nodes:
+// - checkpoint lag - the checkpoint is not too far behind the cluster head
+// - `sui_getCheckpoint` - the latest checkpoint is recent
+// - `sui_getChainIdentifier` - the node reports its chain
+func (suiFamily) LivenessChecks(h *RPCHealthchecker) []probeCheck {
+	return []probeCheck{
+		{name: "checkpointLag", weight: 1, run: h.checkSuiCheckpointLag},
+		{name: "sui_getCheckpoint", weight: 1, run: h.checkSuiCheckpointAge},
+		{name: "sui_getChainIdentifier", weight: 1, run: h.checkSuiChainIdentifier},
+	}
+}
+
+// Exceptions are the errors of the nodes which have not executed a recent
+// transaction yet.
+func (suiFamily) Exceptions() []Exception {
+	return []Exception{
+		{Match: "Could not find the referenced transaction", Message: "Sui: transaction not found"},
+	}
+}
+
+func (suiFamily) WebsocketOnNextPort() bool {
+	return false
+}
+
+// checkSuiCheckpointNumber returns the latest checkpoint, which Sui encodes
+// as a decimal string.
+func (h *RPCHealthchecker) checkSuiCheckpointNumber(ctx context.Context) (uint64, error) {
+	var sequenceNumber string
+	if err := h.client.CallContext(ctx, &sequenceNumber, "sui_getLatestCheckpointSequenceNumber"); err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(sequenceNumber, 10, 64)
+}
+
+func (h *RPCHealthchecker) checkSuiCheckpointLag() error {
+	maxLag := h.config.SuiHealth.MaxCheckpointLag
+	if maxLag == 0 {
+		maxLag = defaultMaxCheckpointLag
+	}
+
+	return h.checkHeadLag(maxLag)
+}
+
+// checkSuiCheckpointAge fails when the last checkpoint of the node is older
+// than the max age.
+func (h *RPCHealthchecker) checkSuiCheckpointAge() error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	checkpoint, err := h.checkSuiCheckpointNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		TimestampMs string `json:"timestampMs"`
+	}
+	if err := h.client.CallContext(ctx, &result, "sui_getCheckpoint", strconv.FormatUint(checkpoint, 10)); err != nil {
+		return err
+	}
+	timestamp, err := strconv.ParseInt(result.TimestampMs, 10, 64)
+	if err != nil {
+		return fmt.Errorf("checkpoint %d: invalid timestamp %q", checkpoint, result.TimestampMs)
+	}
+
+	maxAge := h.config.SuiHealth.MaxCheckpointAge
+	if maxAge == 0 {
+		maxAge = defaultMaxCheckpointAge
+	}
+	if age := time.Since(time.UnixMilli(timestamp)); age > maxAge {
+		return fmt.Errorf("checkpoint %d is %s old", checkpoint, age.Round(time.Second))
+	}
+
+	return nil
+}
+
+func (h *RPCHealthchecker) checkSuiChainIdentifier() error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	var chainIdentifier string
+	if err := h.client.CallContext(ctx, &chainIdentifier, "sui_getChainIdentifier"); err != nil {
+		return err
+	}
+	if chainIdentifier == "" {
+		return errors.New("no chain identifier")
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuiHealthProfile(t *testing.T) {
+	checkpointTimestamp := time.Now()
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request RPCRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		result := ""
+		switch request.Method {
+		case "sui_getLatestCheckpointSequenceNumber":
+			result = `"41962355"`
+		case "sui_getCheckpoint":
+			result = `{"sequenceNumber":"41962355","timestampMs":"` + strconv.FormatInt(checkpointTimestamp.UnixMilli(), 10) + `"}`
+		case "sui_getChainIdentifier":
+			result = `"35834a8a"`
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(request.ID) + `,"result":` + result + `}`))
+	}))
+	defer fakeRPCServer.Close()
+
+	head := uint64(41962400)
+	healthchecker, err := NewHealthchecker(RPCHealthcheckerConfig{
+		URL:              fakeRPCServer.URL,
+		Name:             "Sui",
+		Chain:            ChainSui,
+		Timeout:          time.Second,
+		FailureThreshold: 1,
+		SuccessThreshold: 1,
+		SuiHealth: SuiHealthConfig{
+			MaxCheckpointLag: 100,
+			MaxCheckpointAge: time.Minute,
+		},
+		ClusterHead: func() uint64 { return head },
+	})
+	assert.Nil(t, err)
+	rpcHealthchecker := healthchecker.(*RPCHealthchecker)
+
+	rpcHealthchecker.checkAndSetBlockNumberHealth()
+	assert.Equal(t, uint64(41962355), healthchecker.BlockNumber())
+
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.True(t, healthchecker.IsHealthy())
+
+	// A node behind the cluster head is unhealthy.
+	head = 41962600
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.False(t, healthchecker.IsHealthy())
+	head = 41962400
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.True(t, healthchecker.IsHealthy())
+
+	// So is a node stuck on an old checkpoint.
+	checkpointTimestamp = time.Now().Add(-2 * time.Minute)
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.False(t, healthchecker.IsHealthy())
+}
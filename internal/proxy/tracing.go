@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/0xProject/rpc-gateway/internal/proxy"
+
+// startRequestSpan starts the span covering a client request and all of its
+// attempts, continuing the trace of the client when it sent a trace context.
+func startRequestSpan(r *http.Request) (*http.Request, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "rpc.request",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(methodAttributes(r)...),
+	)
+
+	return r.WithContext(context.WithValue(ctx, RequestSpan, span)), span
+}
+
+// startAttemptSpan starts the span of a single upstream attempt, a sibling
+// of the previous attempts under the request span.
+func startAttemptSpan(r *http.Request, target *HTTPTarget) (*http.Request, trace.Span) {
+	ctx := r.Context()
+	if span, ok := ctx.Value(RequestSpan).(trace.Span); ok {
+		ctx = trace.ContextWithSpan(ctx, span)
+	}
+
+	attributes := append(methodAttributes(r),
+		attribute.String("rpc.target", target.Config.Name),
+		attribute.Int("rpc.attempt", len(GetVisitedTargetsFromContext(r))+1),
+	)
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "rpc.attempt",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+
+	return r.WithContext(ctx), span
+}
+
+// endAttemptSpan ends the attempt made with request, recording why it is
+// retried on a different target.
+func endAttemptSpan(r *http.Request, reason error) {
+	span := trace.SpanFromContext(r.Context())
+	if reason != nil {
+		span.SetAttributes(attribute.String("rpc.retry_reason", reason.Error()))
+		span.SetStatus(codes.Error, reason.Error())
+	}
+	span.End()
+}
+
+func methodAttributes(r *http.Request) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{attribute.String("rpc.system", "jsonrpc")}
+
+	body := GetRequestBodyFromContext(r)
+	if body == nil {
+		return attributes
+	}
+	if request, ok := body.SingleRequest(); ok {
+		return append(attributes, attribute.String("rpc.method", request.Method))
+	}
+
+	methods := make([]string, 0, len(body.Requests))
+	for _, request := range body.Requests {
+		methods = append(methods, request.Method)
+	}
+
+	return append(attributes,
+		attribute.StringSlice("rpc.methods", methods),
+		attribute.Int("rpc.batch_size", len(body.Requests)),
+	)
+}
+
+// injectTraceContext passes the trace context on to the upstream.
+func injectTraceContext(r *http.Request) {
+	otel.GetTextMapPropagator().Inject(r.Context(), propagation.HeaderCarrier(r.Header))
+}
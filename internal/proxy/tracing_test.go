@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingSpansPerAttempt(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	tracerProvider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	// The first attempt is rate limited, the second matches an
+	// exception and the third succeeds.
+	var calls atomic.Int32
+	var traceparents []string
+	rpcGatewayConfig := createConfig()
+	for i := 1; i <= 3; i++ {
+		fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			traceparents = append(traceparents, r.Header.Get("Traceparent"))
+			switch calls.Add(1) {
+			case 1:
+				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			case 2:
+				w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"error match string"}}`))
+			default:
+				w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+			}
+		}))
+		defer fakeRPCServer.Close()
+
+		rpcGatewayConfig.Targets = append(rpcGatewayConfig.Targets, TargetConfig{
+			Name: fmt.Sprintf("Server%d", i),
+			Connection: TargetConfigConnection{
+				HTTP: TargetConnectionHTTP{
+					URL: fakeRPCServer.URL,
+				},
+			},
+		})
+	}
+	rpcGatewayConfig.Exceptions = []Exception{
+		{
+			Match:   "error match string",
+			Message: "error message",
+		},
+	}
+	healthcheckManager := NewHealthcheckManager(HealthcheckManagerConfig{
+		Targets: rpcGatewayConfig.Targets,
+		Config:  rpcGatewayConfig.HealthChecks,
+	})
+
+	httpFailoverProxy := NewProxy(rpcGatewayConfig, healthcheckManager)
+
+	req, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId"}`))
+	assert.Nil(t, err)
+	req.Header.Set("Traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	rr := httptest.NewRecorder()
+	httpFailoverProxy.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 4)
+
+	root := spans[len(spans)-1]
+	assert.Equal(t, "rpc.request", root.Name())
+	assert.Equal(t, traceID, root.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", root.Parent().SpanID().String())
+	assert.Contains(t, root.Attributes(), attribute.String("rpc.method", "eth_chainId"))
+
+	attempts := spans[:3]
+	for i, attempt := range attempts {
+		assert.Equal(t, "rpc.attempt", attempt.Name())
+		assert.Equal(t, root.SpanContext().SpanID(), attempt.Parent().SpanID())
+		assert.Contains(t, attempt.Attributes(), attribute.Int("rpc.attempt", i+1))
+
+		// The upstream continues the trace from the attempt.
+		assert.True(t, strings.HasPrefix(traceparents[i], "00-"+traceID+"-"+attempt.SpanContext().SpanID().String()))
+	}
+
+	assert.Contains(t, attempts[0].Attributes(), attribute.Int("http.response.status_code", http.StatusTooManyRequests))
+	assert.Contains(t, attempts[0].Attributes(), attribute.String("rpc.retry_reason", "rate limited"))
+	assert.Equal(t, codes.Error, attempts[0].Status().Code)
+
+	assert.Contains(t, attempts[1].Attributes(), attribute.String("rpc.exception", "error message"))
+	assert.Contains(t, attempts[1].Attributes(), attribute.String("rpc.retry_reason", "error message"))
+
+	assert.Contains(t, attempts[2].Attributes(), attribute.Int("http.response.status_code", http.StatusOK))
+	assert.Equal(t, codes.Unset, attempts[2].Status().Code)
+}
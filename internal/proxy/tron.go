@@ -0,0 +1,166 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// About a minute of 3s blocks.
+	defaultMaxTronBlockLag = 20
+	defaultMaxTronBlockAge = time.Minute
+)
+
+// tronFamily serves Tron. The targets are the JSON-RPC endpoint of the nodes
+// (`/jsonrpc`), the head is read from the HTTP API next to it, which has
+// the timestamp of the blocks too.
+type tronFamily struct{}
+
+func (tronFamily) HeadHeight(ctx context.Context, h *RPCHealthchecker) (uint64, error) {
+	start := time.Now()
+	block, err := h.getTronNowBlock(ctx)
+	if err != nil {
+		zap.L().Warn("error fetching the block number", zap.Error(err), zap.String("name", h.config.Name))
+		return 0, err
+	}
+	h.observeHead("getnowblock", start, block.Number)
+	zap.L().Debug("fetched block", zap.Uint64("blockNumber", block.Number), zap.String("rpcProvider", h.config.Name))
+
+	return block.Number, nil
+}
+
+// LivenessChecks of Tron nodes:
+// - `/wallet/getnowblock` - the latest block is recent
+// - block lag - the block is not too far behind the cluster head
+// - `eth_blockNumber` - the JSON-RPC endpoint is served
+func (tronFamily) LivenessChecks(h *RPCHealthchecker) []probeCheck {
+	return []probeCheck{
+		{name: "getnowblock", weight: 1, run: h.checkTronBlockAge},
+		{name: "blockLag", weight: 1, run: h.checkTronBlockLag},
+		{name: "eth_blockNumber", weight: 1, run: h.checkTronJSONRPC},
+	}
+}
+
+// Exceptions are the errors of overloaded nodes and of nodes cut from the
+// network.
+func (tronFamily) Exceptions() []Exception {
+	return []Exception{
+		{Match: "SERVER_BUSY", Message: "Tron: server busy"},
+		{Match: "NOT_ENOUGH_EFFECTIVE_CONNECTION", Message: "Tron: not enough effective connection"},
+	}
+}
+
+func (tronFamily) WebsocketOnNextPort() bool {
+	return false
+}
+
+type tronBlock struct {
+	Number    uint64
+	Timestamp time.Time
+}
+
+// getTronNowBlock fetches the latest block from the HTTP API.
+func (h *RPCHealthchecker) getTronNowBlock(ctx context.Context) (*tronBlock, error) {
+	endpoint, err := tronAPIURL(h.config.URL, "/wallet/getnowblock")
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBufferString("{}"))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", userAgent)
+
+	resp, err := h.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyContent, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("got non-200 response, status: %d, body: %s", resp.StatusCode, bodyContent)
+	}
+
+	var result struct {
+		BlockHeader struct {
+			RawData struct {
+				Number    uint64 `json:"number"`
+				Timestamp int64  `json:"timestamp"`
+			} `json:"raw_data"`
+		} `json:"block_header"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if result.BlockHeader.RawData.Number == 0 {
+		return nil, errors.New("no block")
+	}
+
+	return &tronBlock{
+		Number:    result.BlockHeader.RawData.Number,
+		Timestamp: time.UnixMilli(result.BlockHeader.RawData.Timestamp),
+	}, nil
+}
+
+func (h *RPCHealthchecker) checkTronBlockAge() error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	block, err := h.getTronNowBlock(ctx)
+	if err != nil {
+		return err
+	}
+
+	maxAge := h.config.TronHealth.MaxBlockAge
+	if maxAge == 0 {
+		maxAge = defaultMaxTronBlockAge
+	}
+	if age := time.Since(block.Timestamp); age > maxAge {
+		return fmt.Errorf("block %d is %s old", block.Number, age.Round(time.Second))
+	}
+
+	return nil
+}
+
+func (h *RPCHealthchecker) checkTronBlockLag() error {
+	maxLag := h.config.TronHealth.MaxBlockLag
+	if maxLag == 0 {
+		maxLag = defaultMaxTronBlockLag
+	}
+
+	return h.checkHeadLag(maxLag)
+}
+
+func (h *RPCHealthchecker) checkTronJSONRPC() error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	_, err := h.checkBlockNumber(ctx)
+
+	return err
+}
+
+// tronAPIURL returns the URL of an HTTP API path of the node serving the
+// JSON-RPC endpoint rpcURL.
+func tronAPIURL(rpcURL string, path string) (string, error) {
+	endpoint, err := url.Parse(rpcURL)
+	if err != nil {
+		return "", err
+	}
+	endpoint.Path = strings.TrimSuffix(strings.TrimSuffix(endpoint.Path, "/"), "/jsonrpc") + path
+
+	return endpoint.String(), nil
+}
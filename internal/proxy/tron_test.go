@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTronHealthProfile(t *testing.T) {
+	blockTimestamp := time.Now()
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/wallet/getnowblock":
+			fmt.Fprintf(w, `{"blockID":"0000000003d5ca6f","block_header":{"raw_data":{"number":64342639,"timestamp":%d}}}`, blockTimestamp.UnixMilli())
+		case "/jsonrpc":
+			var request RPCRequest
+			json.NewDecoder(r.Body).Decode(&request)
+			w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(request.ID) + `,"result":"0x3d5ca6f"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer fakeRPCServer.Close()
+
+	head := uint64(64342640)
+	healthchecker, err := NewHealthchecker(RPCHealthcheckerConfig{
+		URL:              fakeRPCServer.URL + "/jsonrpc",
+		Name:             "Tron",
+		Chain:            ChainTron,
+		Timeout:          time.Second,
+		FailureThreshold: 1,
+		SuccessThreshold: 1,
+		ClusterHead:      func() uint64 { return head },
+	})
+	assert.Nil(t, err)
+	rpcHealthchecker := healthchecker.(*RPCHealthchecker)
+
+	rpcHealthchecker.checkAndSetBlockNumberHealth()
+	assert.Equal(t, uint64(64342639), healthchecker.BlockNumber())
+
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.True(t, healthchecker.IsHealthy())
+
+	// A node behind the cluster head is unhealthy.
+	head = 64342700
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.False(t, healthchecker.IsHealthy())
+	head = 64342640
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.True(t, healthchecker.IsHealthy())
+
+	// So is a node stuck on an old block.
+	blockTimestamp = time.Now().Add(-2 * time.Minute)
+	rpcHealthchecker.checkAndSetProbesHealth()
+	assert.False(t, healthchecker.IsHealthy())
+}
+
+func TestTronAPIURL(t *testing.T) {
+	for rpcURL, expected := range map[string]string{
+		"https://api.trongrid.io/jsonrpc":  "https://api.trongrid.io/wallet/getnowblock",
+		"https://api.trongrid.io/jsonrpc/": "https://api.trongrid.io/wallet/getnowblock",
+		"http://localhost:8090":            "http://localhost:8090/wallet/getnowblock",
+	} {
+		endpoint, err := tronAPIURL(rpcURL, "/wallet/getnowblock")
+		assert.Nil(t, err)
+		assert.Equal(t, expected, endpoint)
+	}
+}
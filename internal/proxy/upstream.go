@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Call sends a JSON-RPC body straight to the target, bypassing the failover
+// logic of the reverse proxy. It's meant for requests the gateway issues on
+// its own behalf, e.g. when fanning out a request to many targets.
+func (t *HTTPTarget) Call(ctx context.Context, body []byte) ([]byte, error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "rpc.call",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("rpc.target", t.Config.Name)),
+	)
+	defer span.End()
+
+	data, err := t.call(ctx, body)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return data, err
+}
+
+func (t *HTTPTarget) call(ctx context.Context, body []byte) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, t.Config.Connection.HTTP.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create request")
+	}
+	request.Header.Set("Content-Type", "application/json")
+	injectTraceContext(request)
+	forwardRequestID(ctx, request.Header)
+
+	resp, err := t.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read body")
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return data, fmt.Errorf("got non-2xx response, status: %d", resp.StatusCode)
+	}
+
+	return data, nil
+}
+
+// GetHealthyTargets returns the targets requests can be routed to right now,
+// the ones that are neither disabled, shadow nor unhealthy.
+func (h *Proxy) GetHealthyTargets() []*HTTPTarget {
+	var targets []*HTTPTarget
+	for _, target := range h.targets {
+		if target.Config.IsDisabled || target.Config.Shadow || !h.healthcheckManager.IsTargetHealthy(target.Config.Name) {
+			continue
+		}
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// routableTargets returns the targets of group allowed to serve request, the
+// way getNextTargetInGroup would pick them: the healthy ones, or the ones not
+// checked yet when none is.
+func (h *Proxy) routableTargets(r *http.Request, group string) []*HTTPTarget {
+	required := h.requiredFilter(r, group)
+	for _, accept := range []func(Healthchecker) bool{Healthchecker.IsHealthy, isUnknown} {
+		var targets []*HTTPTarget
+		for i, target := range h.targets {
+			healthchecker := h.healthcheckManager.healthcheckers[i]
+			if target.Config.IsDisabled || !accept(healthchecker) || !required(i, healthchecker) {
+				continue
+			}
+			targets = append(targets, target)
+		}
+
+		if len(targets) > 0 {
+			return targets
+		}
+	}
+
+	return nil
+}
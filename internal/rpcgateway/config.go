@@ -3,6 +3,7 @@ package rpcgateway
 import (
 	"github.com/0xProject/rpc-gateway/internal/metrics"
 	"github.com/0xProject/rpc-gateway/internal/proxy"
+	"github.com/0xProject/rpc-gateway/internal/tracing"
 )
 
 type RPCGatewayConfig struct { //nolint:revive
@@ -11,5 +12,19 @@ type RPCGatewayConfig struct { //nolint:revive
 	HealthChecks proxy.HealthCheckConfig `yaml:"healthChecks"`
 	Targets      []proxy.TargetConfig    `yaml:"targets"`
 	Exceptions   []proxy.Exception       `yaml:"exceptions"`
-	Solana       bool                    `yaml:"solana"`
+	// Chain family of the targets, "evm" (the default), "solana", "tron"
+	// or "sui".
+	Chain string `yaml:"chain"`
+	// Chain family by target group, overriding Chain.
+	Chains map[string]string `yaml:"chains"`
+	// Deprecated: use `chain: solana`.
+	Solana  bool           `yaml:"solana"`
+	Tracing tracing.Config `yaml:"tracing"`
+}
+
+func (c RPCGatewayConfig) chainConfig() proxy.ChainConfig {
+	return proxy.ChainConfig{
+		Default: c.Chain,
+		Groups:  c.Chains,
+	}
 }
@@ -2,6 +2,7 @@ package rpcgateway
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -9,18 +10,23 @@ import (
 	"strconv"
 	"time"
 
+	gatewaymetrics "github.com/0xProject/rpc-gateway/internal/metrics"
 	"github.com/0xProject/rpc-gateway/internal/proxy"
 	"github.com/gorilla/mux"
 	"github.com/mwitkow/go-conntrack"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/purini-to/zapmw"
 	metrics "github.com/slok/go-http-metrics/metrics/prometheus"
 	"github.com/slok/go-http-metrics/middleware"
 	"github.com/slok/go-http-metrics/middleware/std"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/sync/errgroup"
 	"gopkg.in/yaml.v2"
 )
 
+const defaultShutdownTimeout = 30 * time.Second
+
 type RPCGateway struct {
 	config             RPCGatewayConfig
 	httpFailoverProxy  *proxy.Proxy
@@ -49,7 +55,7 @@ func (r *RPCGateway) Start(ctx context.Context) error {
 		zap.L().Error("Failed parse port number", zap.Error(err))
 	}
 
-	if r.config.Solana {
+	if r.httpFailoverProxy.ServesWebsocketsOnNextPort() {
 		go func() {
 			wsListenAddress := fmt.Sprintf(":%d", portNumber+1)
 
@@ -60,7 +66,7 @@ func (r *RPCGateway) Start(ctx context.Context) error {
 			}
 			wsListener := conntrack.NewListener(listener, conntrack.TrackWithTracing())
 			err = r.wsServer.Serve(wsListener)
-			if err != nil {
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
 				panic(err)
 			}
 		}()
@@ -74,19 +80,51 @@ func (r *RPCGateway) Start(ctx context.Context) error {
 		zap.L().Error("Failed to listen", zap.Error(err))
 	}
 	httpListener := conntrack.NewListener(listener, conntrack.TrackWithTracing())
-	return r.server.Serve(httpListener)
+	if err := r.server.Serve(httpListener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
 }
 
+// Stop shuts the gateway down gracefully: after the shutdown delay, it
+// stops accepting connections and waits for the requests and websockets in
+// flight until the shutdown timeout, then stops the health checks.
 func (r *RPCGateway) Stop(ctx context.Context) error {
 	zap.L().Info("stopping rpc gateway")
-	err := r.healthcheckManager.Stop(ctx)
+
+	select {
+	case <-time.After(r.config.Proxy.ShutdownDelay):
+	case <-ctx.Done():
+	}
+
+	timeout := r.config.Proxy.ShutdownTimeout
+	if timeout == 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var g errgroup.Group
+	g.Go(func() error {
+		return r.server.Shutdown(ctx)
+	})
+	g.Go(func() error {
+		return r.wsServer.Shutdown(ctx)
+	})
+	err := g.Wait()
 	if err != nil {
+		zap.L().Warn("requests in flight dropped on shutdown", zap.Error(err))
+	}
+	if err := r.httpFailoverProxy.DrainWebsockets(ctx); err != nil {
+		zap.L().Warn("websockets in flight closed on shutdown", zap.Error(err))
+	}
+
+	if err := r.healthcheckManager.Stop(ctx); err != nil {
 		zap.L().Error("healthcheck manager failed to stop gracefully", zap.Error(err))
 	}
-	go func() error {
-		return r.wsServer.Close()
-	}()
-	return r.server.Close()
+
+	return err
 }
 
 func (r *RPCGateway) GetCurrentTarget() string {
@@ -101,6 +139,14 @@ func (r *RPCGateway) GetBlockNumberByName(name string) uint64 {
     return 0
 }
 
+func (r *RPCGateway) GetCapabilitiesByName(name string) map[string]bool {
+	healthChecker := r.healthcheckManager.GetTargetByName(name)
+	if healthChecker != nil {
+		return healthChecker.Capabilities()
+	}
+	return nil
+}
+
 func (h *RPCGateway) GetTargetConfigs() []proxy.TargetConfig {
     return h.httpFailoverProxy.GetTargetConfigs()
 }
@@ -113,12 +159,40 @@ func (r *RPCGateway) UpdateTargetStatus(targetconfig *proxy.TargetConfig, isDisa
     targetconfig.IsDisabled = isDisabled
 }
 
+func (r *RPCGateway) GetStickySessions() []proxy.StickySession {
+	return r.httpFailoverProxy.GetStickySessions()
+}
+
+// Readiness reports whether the gateway can serve traffic, on /readyz.
+func (r *RPCGateway) Readiness() gatewaymetrics.ReadinessReport {
+	return r.httpFailoverProxy.Readiness()
+}
+
+func (r *RPCGateway) GetSLOs() []proxy.TargetSLO {
+	return r.healthcheckManager.GetSLOs()
+}
+
+func (r *RPCGateway) AddDebugRule(target, method string, duration time.Duration) proxy.DebugRule {
+	return r.httpFailoverProxy.AddDebugRule(target, method, duration)
+}
+
+func (r *RPCGateway) GetDebugRules() []proxy.DebugRule {
+	return r.httpFailoverProxy.GetDebugRules()
+}
+
+func (r *RPCGateway) ClearDebugRules() {
+	r.httpFailoverProxy.ClearDebugRules()
+}
+
 func NewRPCGateway(config RPCGatewayConfig) *RPCGateway {
+	registry := gatewaymetrics.NewRegistry(config.Metrics, prometheus.DefaultRegisterer)
+
 	healthcheckManager := proxy.NewHealthcheckManager(
 		proxy.HealthcheckManagerConfig{
 			Targets: config.Targets,
 			Config:  config.HealthChecks,
-			Solana:  config.Solana,
+			Chain:   config.chainConfig(),
+			Metrics: registry,
 		})
 	httpFailoverProxy := proxy.NewProxy(
 		proxy.Config{
@@ -126,7 +200,8 @@ func NewRPCGateway(config RPCGatewayConfig) *RPCGateway {
 			Targets:      config.Targets,
 			HealthChecks: config.HealthChecks,
 			Exceptions:   config.Exceptions,
-			Solana:       config.Solana,
+			Chain:        config.chainConfig(),
+			Metrics:      registry,
 		},
 		healthcheckManager,
 	)
@@ -188,6 +263,17 @@ func NewRPCGatewayFromConfigBytes(configBytes []byte) (*RPCGatewayConfig, error)
 		return nil, err
 	}
 
+	if config.Solana && config.Chain == "" {
+		zap.L().Warn("the solana key is deprecated, use `chain: solana` instead")
+		config.Chain = proxy.ChainSolana
+	}
+	if err := config.chainConfig().Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.Proxy.Consensus.Validate(); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
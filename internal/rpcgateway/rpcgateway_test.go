@@ -7,9 +7,11 @@ import (
 	proxy2 "github.com/0xProject/rpc-gateway/internal/proxy"
 	"html/template"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	toxiproxy "github.com/Shopify/toxiproxy/client"
 	"github.com/prometheus/client_golang/prometheus"
@@ -144,3 +146,88 @@ func TestRpcGatewayFailover(t *testing.T) {
 	err = gateway.Stop(context.TODO())
 	assert.Nil(t, err)
 }
+
+func TestChainConfig(t *testing.T) {
+	config, err := NewRPCGatewayFromConfigString("solana: true\n")
+	assert.Nil(t, err)
+	assert.Equal(t, proxy2.ChainSolana, config.Chain)
+
+	config, err = NewRPCGatewayFromConfigString("chain: evm\nchains:\n  sol: solana\n")
+	assert.Nil(t, err)
+	assert.Equal(t, proxy2.ChainSolana, config.chainConfig().For(proxy2.TargetConfig{Group: "sol"}))
+
+	_, err = NewRPCGatewayFromConfigString("chain: bitcoin\n")
+	assert.NotNil(t, err)
+}
+
+func TestConsensusConfig(t *testing.T) {
+	_, err := NewRPCGatewayFromConfigString("proxy:\n  consensus:\n    targets: 2\n    quorum: 3\n")
+	assert.EqualError(t, err, "consensus: quorum of 3 exceeds the 2 targets asked")
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	received := make(chan struct{})
+	fakeRPCServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "rpc-gateway-health-check" {
+			close(received)
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer fakeRPCServer.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	config, err := NewRPCGatewayFromConfigString(fmt.Sprintf(`
+proxy:
+  port: %d
+  shutdownTimeout: "5s"
+healthChecks:
+  interval: "1h"
+  timeout: "1s"
+targets:
+  - name: "Node"
+    connection:
+      http:
+        url: "%s"
+`, port, fakeRPCServer.URL))
+	assert.Nil(t, err)
+
+	gateway := NewRPCGateway(*config)
+	started := make(chan error)
+	go func() {
+		started <- gateway.Start(context.Background())
+	}()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d", port)
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	responses := make(chan *http.Response)
+	go func() {
+		res, err := http.Post(url, "application/json", bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId"}`))
+		assert.Nil(t, err)
+		responses <- res
+	}()
+	<-received
+
+	// The request in flight is answered, new connections are refused.
+	assert.Nil(t, gateway.Stop(context.Background()))
+	res := <-responses
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+	assert.Nil(t, <-started)
+
+	_, err = http.Post(url, "application/json", bytes.NewBufferString(`{}`))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,19 @@
+package tracing
+
+// Config selects where the spans are exported. Tracing is disabled when no
+// exporter is set.
+type Config struct {
+	// Either otlp, stdout or file.
+	Exporter string `yaml:"exporter"`
+	// OTLP/HTTP collector address, e.g. localhost:4318.
+	Endpoint string            `yaml:"endpoint"`
+	Insecure bool              `yaml:"insecure"`
+	Headers  map[string]string `yaml:"headers"`
+	// Path of the file the file exporter writes to.
+	File string `yaml:"file"`
+	// Fraction of the requests traced, defaults to 1. The decision of a
+	// client sending a trace context is always followed.
+	SampleRatio float64 `yaml:"sampleRatio"`
+	// Defaults to rpc-gateway.
+	ServiceName string `yaml:"serviceName"`
+}
@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+)
+
+func TestFileExporter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "spans.json")
+
+	shutdown, err := Setup(context.Background(), Config{
+		Exporter: "file",
+		File:     file,
+	})
+	assert.Nil(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "rpc.request")
+	span.End()
+	assert.Nil(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(file)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"Name":"rpc.request"`)
+	assert.Contains(t, string(data), `"Value":"rpc-gateway"`)
+}
+
+func TestUnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), Config{Exporter: "jaeger"})
+	assert.NotNil(t, err)
+}